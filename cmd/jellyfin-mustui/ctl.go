@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/ctl"
+)
+
+const ctlUsage = `Usage: jellyfin-mustui ctl [flags] <command> [arg]
+
+Commands:
+  play-pause        toggle playback
+  play, pause, stop start, pause or stop playback
+  next, previous    skip tracks
+  seek <pos>        seek to 90 or 1:30, or by +10 / -10 seconds
+  volume <level>    set the volume to 50, or change it by +5 / -5 percent
+  mute [on|off]     toggle or set mute
+  shuffle [on|off]  toggle or set shuffle
+  repeat [mode]     cycle repeat, or set it to off, all or one
+  clear             empty the queue
+  enqueue <id>      add a track, album, playlist or artist to the queue
+  status            print what is playing
+
+Flags:
+`
+
+// runCtl sends one command to the running player and returns the exit code.
+func runCtl(args []string) int {
+	fs := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	format := fs.String("format", "", "print status with a Go template, e.g. '{{.Artist}} - {{.Title}}'")
+	socket := fs.String("socket", ctl.SocketPath(), "control socket path")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), ctlUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	req := ctl.Request{Command: fs.Arg(0), Arg: fs.Arg(1)}
+	resp, err := ctl.Send(*socket, req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if resp.Status == nil {
+		return 0
+	}
+
+	if *format != "" {
+		tmpl, err := template.New("status").Parse(*format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid format: %v\n", err)
+			return 2
+		}
+		if err := tmpl.Execute(os.Stdout, resp.Status); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println()
+		return 0
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(resp.Status)
+	return 0
+}
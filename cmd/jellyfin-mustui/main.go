@@ -1,26 +1,115 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"sync/atomic"
 
+	"github.com/cedev-1/jellyfin-mustui/internal/cast"
 	"github.com/cedev-1/jellyfin-mustui/internal/config"
+	"github.com/cedev-1/jellyfin-mustui/internal/ctl"
+	"github.com/cedev-1/jellyfin-mustui/internal/daemon"
 	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/cedev-1/jellyfin-mustui/internal/mpris"
+	"github.com/cedev-1/jellyfin-mustui/internal/offline"
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
 	"github.com/cedev-1/jellyfin-mustui/internal/tui"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		os.Exit(runCtl(os.Args[2:]))
+	}
+
+	daemonMode := flag.Bool("daemon", false, "play without the interface, controlled through the control socket")
+	attach := flag.Bool("attach", false, "control a running daemon instead of playing locally")
+	profile := flag.String("profile", "", "log in with the named server profile")
+	flag.Parse()
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	if *profile != "" {
+		if err := cfg.Use(*profile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	device := jellyfin.Device{ID: cfg.DeviceID, Name: cfg.DeviceName}
+	client := jellyfin.NewClient(cfg.ServerURL, cfg.Token, cfg.UserID, device)
+	if timeout := cfg.RequestTimeout(); timeout > 0 {
+		client.Timeout = timeout
+	}
+	if cfg.RequestRetries != nil {
+		client.Retries = *cfg.RequestRetries
+	}
+
+	// Without a usable cache directory the player only streams.
+	cache, _ := offline.OpenDefault(cfg.ServerURL, cfg.UserID, cfg.OfflineLimit())
+
+	if *daemonMode {
+		if err := daemon.Run(cfg, client, cache); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	client := jellyfin.NewClient(cfg.ServerURL, cfg.Token, cfg.UserID)
+	if *attach {
+		m := tui.NewModel(cfg, client, ctl.NewRemote(ctl.SocketPath()), cache)
+		if _, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion()).Run(); err != nil {
+			fmt.Printf("Error running program: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	audio := player.New()
+	if cache != nil {
+		audio.LocalFile = cache.Path
+	}
+	m := tui.NewModel(cfg, client, audio, cache)
+	if *profile == "" && len(cfg.Profiles) > 1 {
+		m = m.PickProfile()
+	}
+
+	// Cover art and remote control follow the server the active profile
+	// points at.
+	var current atomic.Pointer[jellyfin.Client]
+	current.Store(client)
+	var remote *cast.Listener
+	m.OnClientChange(func(c *jellyfin.Client) {
+		current.Store(c)
+		if remote != nil {
+			remote.SetClient(c)
+		}
+	})
+
+	// Media keys are a nice-to-have: without a session bus the player still
+	// works from the keyboard.
+	if server, err := mpris.Start(audio, func(t player.Track) string {
+		if t.AlbumID == "" {
+			return ""
+		}
+		return current.Load().GetImageURL(t.AlbumID)
+	}); err == nil {
+		defer server.Close()
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+
+	if server, err := ctl.Listen(ctl.SocketPath(), tui.ControlHandler(p)); err == nil {
+		defer server.Close()
+	}
 
-	m := tui.NewModel(cfg, client)
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	// Other Jellyfin clients can control this session through the server.
+	remote = cast.Start(client, tui.ControlHandler(p), tui.ErrorHandler(p))
+	defer remote.Close()
 
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v\n", err)
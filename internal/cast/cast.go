@@ -0,0 +1,387 @@
+// Package cast lets other Jellyfin clients, such as the web app, control the
+// player through the server. It advertises what the player can do and turns
+// the commands the server relays over the session socket into control
+// requests.
+package cast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/ctl"
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+)
+
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+	// stableConnection is how long a connection must last for the retry
+	// delay to start over.
+	stableConnection = time.Minute
+	// volumeStep is how much VolumeUp and VolumeDown change the volume, in
+	// percent.
+	volumeStep = 5
+	// skipStep is how far Rewind and FastForward seek, in seconds.
+	skipStep = 10
+)
+
+// capabilities lists the commands translated by requests.
+var capabilities = jellyfin.Capabilities{
+	PlayableMediaTypes: []string{"Audio"},
+	SupportedCommands: []string{
+		"Play", "PlayState", "PlayNext",
+		"VolumeUp", "VolumeDown", "SetVolume",
+		"Mute", "Unmute", "ToggleMute",
+		"SetRepeatMode", "SetShuffleQueue",
+	},
+	SupportsMediaControl: true,
+}
+
+// Listener keeps the session socket open and passes the commands it
+// receives to a handler, reconnecting when the connection drops.
+type Listener struct {
+	handler ctl.Handler
+	onError func(error)
+	// ctx is cancelled on Close, abandoning any request in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	client *jellyfin.Client
+	socket *jellyfin.Socket
+	closed bool
+
+	// wake interrupts the wait before reconnecting.
+	wake chan struct{}
+	done chan struct{}
+}
+
+// Start connects client's session to the server in the background and
+// sends every command received to handler, one at a time. Commands that
+// cannot be carried out and lost connections are passed to onError, which
+// may be nil. A token the server refuses is not tried again until
+// SetClient.
+func Start(client *jellyfin.Client, handler ctl.Handler, onError func(error)) *Listener {
+	l := &Listener{
+		handler: handler,
+		onError: onError,
+		client:  client,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	go l.run()
+	return l
+}
+
+// SetClient moves the session to client, after logging in or switching
+// servers.
+func (l *Listener) SetClient(client *jellyfin.Client) {
+	l.mu.Lock()
+	l.client = client
+	socket := l.socket
+	l.mu.Unlock()
+
+	if socket != nil {
+		socket.Close()
+	}
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close disconnects and waits for the listener to stop.
+func (l *Listener) Close() {
+	l.mu.Lock()
+	l.closed = true
+	socket := l.socket
+	l.mu.Unlock()
+
+	l.cancel()
+	if socket != nil {
+		socket.Close()
+	}
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+	<-l.done
+}
+
+func (l *Listener) run() {
+	defer close(l.done)
+	delay := minRetryDelay
+	for {
+		l.mu.Lock()
+		client, closed := l.client, l.closed
+		l.mu.Unlock()
+		if closed {
+			return
+		}
+
+		start := time.Now()
+		var err error
+		if client.Token != "" {
+			err = l.serve(client)
+		}
+		if err != nil && l.current(client) {
+			l.report(fmt.Errorf("remote control: %w", err))
+		}
+		if time.Since(start) > stableConnection {
+			delay = minRetryDelay
+		}
+
+		retry := time.After(delay)
+		if errors.Is(err, jellyfin.ErrUnauthorized) {
+			// Only another login can help.
+			retry = nil
+		}
+		select {
+		case <-l.wake:
+			delay = minRetryDelay
+		case <-retry:
+			delay = min(delay*2, maxRetryDelay)
+		}
+	}
+}
+
+// serve registers the session and handles its commands until the socket
+// closes.
+func (l *Listener) serve(client *jellyfin.Client) error {
+	if err := client.ReportCapabilities(l.ctx, capabilities); err != nil {
+		return err
+	}
+	socket, err := client.OpenSocket(l.ctx)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	if l.closed || l.client != client {
+		l.mu.Unlock()
+		socket.Close()
+		return nil
+	}
+	l.socket = socket
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		if l.socket == socket {
+			l.socket = nil
+		}
+		l.mu.Unlock()
+		socket.Close()
+	}()
+
+	for {
+		msg, err := socket.Read()
+		if err != nil {
+			return err
+		}
+		reqs, err := requests(l.ctx, client, msg)
+		if err != nil {
+			l.fail(msg, err)
+			continue
+		}
+		for _, req := range reqs {
+			if resp := l.handler(req); !resp.OK {
+				l.fail(msg, errors.New(resp.Error))
+				break
+			}
+		}
+	}
+}
+
+// current reports whether the listener is still open and serving client.
+func (l *Listener) current(client *jellyfin.Client) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.closed && l.client == client
+}
+
+// fail reports a command from the server that could not be carried out.
+func (l *Listener) fail(msg jellyfin.SocketMessage, err error) {
+	l.report(fmt.Errorf("remote %s: %w", msg.MessageType, err))
+}
+
+func (l *Listener) report(err error) {
+	if l.onError != nil && l.ctx.Err() == nil {
+		l.onError(err)
+	}
+}
+
+// requests translates a message from the server into control requests. It
+// returns none for messages that are not commands.
+func requests(ctx context.Context, client *jellyfin.Client, msg jellyfin.SocketMessage) ([]ctl.Request, error) {
+	switch msg.MessageType {
+	case "Playstate":
+		var ps jellyfin.PlaystateRequest
+		if err := json.Unmarshal(msg.Data, &ps); err != nil {
+			return nil, err
+		}
+		return playstate(ps), nil
+	case "Play":
+		var play jellyfin.PlayRequest
+		if err := json.Unmarshal(msg.Data, &play); err != nil {
+			return nil, err
+		}
+		return playItems(ctx, client, play)
+	case "GeneralCommand":
+		var cmd jellyfin.GeneralCommand
+		if err := json.Unmarshal(msg.Data, &cmd); err != nil {
+			return nil, err
+		}
+		return generalCommand(cmd), nil
+	}
+	return nil, nil
+}
+
+func playstate(ps jellyfin.PlaystateRequest) []ctl.Request {
+	var req ctl.Request
+	switch ps.Command {
+	case "PlayPause":
+		req.Command = ctl.CmdPlayPause
+	case "Unpause":
+		req.Command = ctl.CmdPlay
+	case "Pause":
+		req.Command = ctl.CmdPause
+	case "Stop":
+		req.Command = ctl.CmdStop
+	case "NextTrack":
+		req.Command = ctl.CmdNext
+	case "PreviousTrack":
+		req.Command = ctl.CmdPrevious
+	case "Seek":
+		req = seekTo(ps.SeekPositionTicks)
+	case "Rewind":
+		req = ctl.Request{Command: ctl.CmdSeek, Arg: fmt.Sprintf("-%d", skipStep)}
+	case "FastForward":
+		req = ctl.Request{Command: ctl.CmdSeek, Arg: fmt.Sprintf("+%d", skipStep)}
+	default:
+		return nil
+	}
+	return []ctl.Request{req}
+}
+
+func seekTo(ticks int64) ctl.Request {
+	return ctl.Request{
+		Command: ctl.CmdSeek,
+		Arg:     fmt.Sprintf("%.3f", jellyfin.TicksToDuration(ticks).Seconds()),
+	}
+}
+
+// playItems looks up the items to play. Albums, playlists and artists
+// stand for all their tracks.
+func playItems(ctx context.Context, client *jellyfin.Client, play jellyfin.PlayRequest) ([]ctl.Request, error) {
+	items, err := client.GetItemsByID(ctx, play.ItemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The server may return the items in another order and leaves out
+	// those it cannot find, so follow the order asked for.
+	byID := make(map[string]jellyfin.MusicItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	var tracks []player.Track
+	start := 0
+	for i, id := range play.ItemIDs {
+		item, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if i == play.StartIndex {
+			start = len(tracks)
+		}
+		if item.Type == "Audio" {
+			tracks = append(tracks, track(item))
+			continue
+		}
+		children, err := client.GetItemTracks(ctx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			tracks = append(tracks, track(child))
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("nothing to play")
+	}
+
+	switch play.PlayCommand {
+	case "PlayNext":
+		return []ctl.Request{{Command: ctl.CmdEnqueueTracks, Arg: "next", Tracks: tracks}}, nil
+	case "PlayLast":
+		return []ctl.Request{{Command: ctl.CmdEnqueueTracks, Tracks: tracks}}, nil
+	}
+
+	var reqs []ctl.Request
+	if play.PlayCommand == "PlayShuffle" {
+		reqs = append(reqs, ctl.Request{Command: ctl.CmdShuffle, Arg: "on"})
+	}
+	reqs = append(reqs, ctl.Request{Command: ctl.CmdPlayTracks, Tracks: tracks, Index: start})
+	if play.StartPositionTicks > 0 {
+		reqs = append(reqs, seekTo(play.StartPositionTicks))
+	}
+	return reqs, nil
+}
+
+// track describes an item for the player. The receiving side fills in the
+// stream URL.
+func track(item jellyfin.MusicItem) player.Track {
+	return player.Track{
+		ID:       item.ID,
+		Name:     item.Name,
+		Artist:   item.AlbumArtist,
+		Album:    item.Album,
+		AlbumID:  item.AlbumID,
+		Duration: jellyfin.TicksToDuration(item.RunTimeTicks),
+	}
+}
+
+func generalCommand(cmd jellyfin.GeneralCommand) []ctl.Request {
+	var req ctl.Request
+	switch cmd.Name {
+	case "SetVolume":
+		req = ctl.Request{Command: ctl.CmdVolume, Arg: cmd.Arguments["Volume"]}
+	case "VolumeUp":
+		req = ctl.Request{Command: ctl.CmdVolume, Arg: fmt.Sprintf("+%d", volumeStep)}
+	case "VolumeDown":
+		req = ctl.Request{Command: ctl.CmdVolume, Arg: fmt.Sprintf("-%d", volumeStep)}
+	case "Mute":
+		req = ctl.Request{Command: ctl.CmdMute, Arg: "on"}
+	case "Unmute":
+		req = ctl.Request{Command: ctl.CmdMute, Arg: "off"}
+	case "ToggleMute":
+		req = ctl.Request{Command: ctl.CmdMute}
+	case "SetRepeatMode":
+		mode, ok := map[string]string{
+			"RepeatNone": player.RepeatOff.String(),
+			"RepeatAll":  player.RepeatAll.String(),
+			"RepeatOne":  player.RepeatOne.String(),
+		}[cmd.Arguments["RepeatMode"]]
+		if !ok {
+			return nil
+		}
+		req = ctl.Request{Command: ctl.CmdRepeat, Arg: mode}
+	case "SetShuffleQueue":
+		arg := "off"
+		if cmd.Arguments["ShuffleMode"] == "Shuffle" {
+			arg = "on"
+		}
+		req = ctl.Request{Command: ctl.CmdShuffle, Arg: arg}
+	default:
+		return nil
+	}
+	return []ctl.Request{req}
+}
@@ -0,0 +1,203 @@
+package cast
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/ctl"
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	"github.com/gorilla/websocket"
+)
+
+// standIn plays the part of a Jellyfin server: it accepts the capabilities,
+// looks up items, and sends msgs to the first session socket opened. Items
+// come back in the reverse of the order asked for, which the server is free
+// to do.
+func standIn(t *testing.T, items map[string]jellyfin.MusicItem, msgs []jellyfin.SocketMessage) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /Sessions/Capabilities/Full", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /Users/{user}/Items", func(w http.ResponseWriter, r *http.Request) {
+		var resp jellyfin.MusicItemsResponse
+		ids := strings.Split(r.URL.Query().Get("Ids"), ",")
+		for i := len(ids) - 1; i >= 0; i-- {
+			if item, ok := items[ids[i]]; ok {
+				resp.Items = append(resp.Items, item)
+			}
+		}
+		resp.TotalRecordCount = len(resp.Items)
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	var once sync.Once
+	upgrader := websocket.Upgrader{}
+	mux.HandleFunc("GET /socket", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api_key") != "token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		once.Do(func() {
+			for _, msg := range msgs {
+				conn.WriteJSON(msg)
+			}
+		})
+		// Hold the socket open until the listener hangs up.
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func message(t *testing.T, kind string, data any) jellyfin.SocketMessage {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jellyfin.SocketMessage{MessageType: kind, Data: raw}
+}
+
+func TestListener(t *testing.T) {
+	items := map[string]jellyfin.MusicItem{
+		"t1": {ID: "t1", Name: "One", Type: "Audio", AlbumArtist: "Band", Album: "Record", AlbumID: "a1", RunTimeTicks: 1800000000},
+		"t2": {ID: "t2", Name: "Two", Type: "Audio", AlbumArtist: "Band", Album: "Record", AlbumID: "a1", RunTimeTicks: 2400000000},
+	}
+	tracks := []player.Track{
+		{ID: "t1", Name: "One", Artist: "Band", Album: "Record", AlbumID: "a1", Duration: 3 * time.Minute},
+		{ID: "t2", Name: "Two", Artist: "Band", Album: "Record", AlbumID: "a1", Duration: 4 * time.Minute},
+	}
+
+	msgs := []jellyfin.SocketMessage{
+		message(t, "Playstate", jellyfin.PlaystateRequest{Command: "PlayPause"}),
+		message(t, "Playstate", jellyfin.PlaystateRequest{Command: "Seek", SeekPositionTicks: 905000000}),
+		message(t, "KeepAlive", nil),
+		message(t, "GeneralCommand", jellyfin.GeneralCommand{Name: "SetVolume", Arguments: map[string]string{"Volume": "40"}}),
+		message(t, "GeneralCommand", jellyfin.GeneralCommand{Name: "SetRepeatMode", Arguments: map[string]string{"RepeatMode": "RepeatOne"}}),
+		message(t, "Play", jellyfin.PlayRequest{ItemIDs: []string{"gone", "t1", "t2"}, StartIndex: 2, PlayCommand: "PlayNow", StartPositionTicks: 300000000}),
+		message(t, "Play", jellyfin.PlayRequest{ItemIDs: []string{"t2"}, PlayCommand: "PlayNext"}),
+		{MessageType: "Play", Data: json.RawMessage(`"not a request"`)},
+		message(t, "Play", jellyfin.PlayRequest{ItemIDs: []string{"missing"}, PlayCommand: "PlayNow"}),
+		message(t, "Playstate", jellyfin.PlaystateRequest{Command: "Stop"}),
+	}
+	want := []ctl.Request{
+		{Command: ctl.CmdPlayPause},
+		{Command: ctl.CmdSeek, Arg: "90.500"},
+		{Command: ctl.CmdVolume, Arg: "40"},
+		{Command: ctl.CmdRepeat, Arg: player.RepeatOne.String()},
+		{Command: ctl.CmdPlayTracks, Tracks: tracks, Index: 1},
+		{Command: ctl.CmdSeek, Arg: "30.000"},
+		{Command: ctl.CmdEnqueueTracks, Arg: "next", Tracks: tracks[1:]},
+		{Command: ctl.CmdStop},
+	}
+
+	srv := standIn(t, items, msgs)
+	client := jellyfin.NewClient(srv.URL, "token", "user", jellyfin.Device{ID: "device"})
+
+	got := make(chan ctl.Request, len(want))
+	errs := make(chan error, 4)
+	l := Start(client, func(req ctl.Request) ctl.Response {
+		got <- req
+		return ctl.Response{OK: true}
+	}, func(err error) {
+		errs <- err
+	})
+	defer l.Close()
+
+	for i, w := range want {
+		select {
+		case req := <-got:
+			if !reflect.DeepEqual(req, w) {
+				t.Errorf("request %d = %+v, want %+v", i, req, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("request %d never arrived", i)
+		}
+	}
+
+	// The malformed Play and the one with nothing to play are reported.
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !strings.HasPrefix(err.Error(), "remote Play: ") {
+				t.Errorf("error %d = %v", i, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("error %d never reported", i)
+		}
+	}
+}
+
+func TestListenerHandlerFailure(t *testing.T) {
+	msgs := []jellyfin.SocketMessage{
+		message(t, "Playstate", jellyfin.PlaystateRequest{Command: "NextTrack"}),
+	}
+	srv := standIn(t, nil, msgs)
+	client := jellyfin.NewClient(srv.URL, "token", "user", jellyfin.Device{ID: "device"})
+
+	errs := make(chan error, 1)
+	l := Start(client, func(req ctl.Request) ctl.Response {
+		return ctl.Errorf("queue is empty")
+	}, func(err error) {
+		errs <- err
+	})
+	defer l.Close()
+
+	select {
+	case err := <-errs:
+		if err.Error() != "remote Playstate: queue is empty" {
+			t.Errorf("error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed request not reported")
+	}
+}
+
+func TestListenerUnauthorized(t *testing.T) {
+	srv := standIn(t, nil, nil)
+	client := jellyfin.NewClient(srv.URL, "expired", "user", jellyfin.Device{ID: "device"})
+
+	errs := make(chan error, 4)
+	l := Start(client, func(req ctl.Request) ctl.Response {
+		return ctl.Response{OK: true}
+	}, func(err error) {
+		errs <- err
+	})
+	defer l.Close()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, jellyfin.ErrUnauthorized) {
+			t.Errorf("error = %v, want ErrUnauthorized", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("refused token not reported")
+	}
+
+	// A refused token is not tried again until the client changes.
+	select {
+	case err := <-errs:
+		t.Errorf("retried with the refused token: %v", err)
+	case <-time.After(2 * minRetryDelay):
+	}
+}
@@ -1,16 +1,46 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 type Config struct {
-	ServerURL string `json:"server_url"`
-	Username  string `json:"username,omitempty"`
-	UserID    string `json:"user_id,omitempty"`
-	Token     string `json:"token,omitempty"`
+	// ServerURL, Username, UserID and Token are those of the active
+	// profile. They are saved as part of it.
+	ServerURL string `json:"-"`
+	Username  string `json:"-"`
+	UserID    string `json:"-"`
+	Token     string `json:"-"`
+
+	// Profile is the name of the active profile, used again on the next
+	// start.
+	Profile  string    `json:"profile,omitempty"`
+	Profiles []Profile `json:"profiles,omitempty"`
+
+	// DeviceID identifies this install to the servers. It is generated on
+	// the first start.
+	DeviceID string `json:"device_id,omitempty"`
+	// DeviceName is shown in the device list of the servers. Empty means
+	// the host name.
+	DeviceName string `json:"device_name,omitempty"`
+
+	// Volume is the last playback volume, from 0 to 1. Nil means full volume.
+	Volume *float64 `json:"volume,omitempty"`
+	// OfflineLimitMB caps the size of the offline cache. Zero means the
+	// default limit.
+	OfflineLimitMB int64 `json:"offline_limit_mb,omitempty"`
+
+	// RequestTimeoutSec limits how long a request to the server may take.
+	// Zero means the default.
+	RequestTimeoutSec int `json:"request_timeout_sec,omitempty"`
+	// RequestRetries is how many times a failed read from the server is
+	// tried again. Nil means the default.
+	RequestRetries *int `json:"request_retries,omitempty"`
 }
 
 const configFileName = "jellyfin-mustui-config.json"
@@ -27,6 +57,17 @@ func getConfigPath() (string, error) {
 	return filepath.Join(appConfigDir, configFileName), nil
 }
 
+// OfflineLimit returns the offline cache limit in bytes, or 0 for the
+// default.
+func (c *Config) OfflineLimit() int64 {
+	return c.OfflineLimitMB << 20
+}
+
+// RequestTimeout returns the request timeout, or 0 for the default.
+func (c *Config) RequestTimeout() time.Duration {
+	return time.Duration(c.RequestTimeoutSec) * time.Second
+}
+
 func LoadConfig() (*Config, error) {
 	path, err := getConfigPath()
 	if err != nil {
@@ -35,7 +76,7 @@ func LoadConfig() (*Config, error) {
 
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return &Config{}, nil
+		return &Config{DeviceID: newDeviceID()}, nil
 	}
 	if err != nil {
 		return nil, err
@@ -45,19 +86,70 @@ func LoadConfig() (*Config, error) {
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	var plain plainTokens
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return nil, err
+	}
+	if len(cfg.Profiles) == 0 {
+		// Config files from before profiles hold a single login at the top
+		// level.
+		var legacy Profile
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, err
+		}
+		if legacy.ServerURL != "" {
+			legacy.Name = DefaultProfile
+			cfg.Profiles = []Profile{legacy}
+			cfg.Profile = DefaultProfile
+			plain.Profiles = append(plain.Profiles, plainToken{Name: DefaultProfile, Token: plain.Token})
+		}
+	}
+	if err := cfg.moveTokens(path, plain.Profiles); err != nil {
+		return nil, err
+	}
+	if cfg.DeviceID == "" {
+		cfg.DeviceID = newDeviceID()
+		if err := writeConfig(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Use(cfg.Profile) != nil && len(cfg.Profiles) > 0 {
+		cfg.Use(cfg.Profiles[0].Name)
+	}
 	return &cfg, nil
 }
 
+func newDeviceID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SaveConfig writes the config file. The token of the active profile goes
+// to the keyring.
 func SaveConfig(cfg *Config) error {
 	path, err := getConfigPath()
 	if err != nil {
 		return err
 	}
+	if cfg.storeActive() {
+		if err := saveToken(cfg.Profile, cfg.Token); err != nil {
+			return err
+		}
+	}
+	return writeConfig(path, cfg)
+}
 
+func writeConfig(path string, cfg *Config) error {
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	// The file names the server and user, so only the user may read it.
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a file written by an earlier version.
+	return os.Chmod(path, 0600)
 }
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// isolate points the config directory at a directory of the test and
+// makes the keyring unreachable, so tokens land in the secrets file. It
+// returns the config directory.
+func isolate(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path="+filepath.Join(home, "no-bus"))
+	return filepath.Join(home, "jellyfin-mustui")
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fileMode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Mode().Perm()
+}
+
+func readSecretsFile(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, secretsFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestLoadConfigMovesTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		secrets string
+		// profile and token are those of the active profile once loaded.
+		profile     string
+		token       string
+		wantSecrets map[string]string
+		rewritten   bool
+	}{
+		{
+			name:        "single login from before profiles",
+			config:      `{"server_url": "https://music.example", "username": "ann", "user_id": "u1", "token": "t1", "device_id": "d"}`,
+			profile:     DefaultProfile,
+			token:       "t1",
+			wantSecrets: map[string]string{DefaultProfile: "t1"},
+			rewritten:   true,
+		},
+		{
+			name:        "profiles with tokens in clear text",
+			config:      `{"profile": "b", "device_id": "d", "profiles": [{"name": "a", "server_url": "https://a.example", "token": "ta"}, {"name": "b", "server_url": "https://b.example", "token": "tb"}]}`,
+			profile:     "b",
+			token:       "tb",
+			wantSecrets: map[string]string{"a": "ta", "b": "tb"},
+			rewritten:   true,
+		},
+		{
+			name:        "profile without a token",
+			config:      `{"profile": "a", "device_id": "d", "profiles": [{"name": "a", "server_url": "https://a.example", "token": ""}]}`,
+			profile:     "a",
+			token:       "",
+			wantSecrets: nil,
+		},
+		{
+			name:        "tokens already moved",
+			config:      `{"profile": "a", "device_id": "d", "profiles": [{"name": "a", "server_url": "https://a.example"}]}`,
+			secrets:     `{"a": "ta"}`,
+			profile:     "a",
+			token:       "ta",
+			wantSecrets: map[string]string{"a": "ta"},
+		},
+		{
+			name:        "unknown active profile",
+			config:      `{"profile": "gone", "device_id": "d", "profiles": [{"name": "a", "server_url": "https://a.example", "token": "ta"}]}`,
+			profile:     "a",
+			token:       "ta",
+			wantSecrets: map[string]string{"a": "ta"},
+			rewritten:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			path := filepath.Join(dir, configFileName)
+			writeFile(t, path, tt.config)
+			if tt.secrets != "" {
+				writeFile(t, filepath.Join(dir, secretsFileName), tt.secrets)
+			}
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Profile != tt.profile || cfg.Token != tt.token {
+				t.Errorf("active profile %q with token %q, want %q with %q", cfg.Profile, cfg.Token, tt.profile, tt.token)
+			}
+			if got := readSecretsFile(t, dir); !reflect.DeepEqual(got, tt.wantSecrets) {
+				t.Errorf("secrets file = %v, want %v", got, tt.wantSecrets)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.rewritten == (string(data) == tt.config) {
+				t.Errorf("config file rewritten = %v, want %v", string(data) != tt.config, tt.rewritten)
+			}
+			if strings.Contains(string(data), `"token"`) && tt.rewritten {
+				t.Errorf("config file still holds a token:\n%s", data)
+			}
+			if tt.rewritten {
+				if mode := fileMode(t, path); mode != 0600 {
+					t.Errorf("config file mode = %v, want 0600", mode)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveTokens(t *testing.T) {
+	dir := isolate(t)
+	path := filepath.Join(dir, configFileName)
+	writeFile(t, path, "{}")
+
+	cfg := &Config{Profiles: []Profile{{Name: "a"}, {Name: "b"}}}
+	tokens := []plainToken{{Name: "a", Token: "ta"}, {Name: "b"}, {Name: "gone", Token: "tg"}}
+	if err := cfg.moveTokens(path, tokens); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profiles[0].Token != "ta" || cfg.Profiles[1].Token != "" {
+		t.Errorf("profile tokens = %q, %q; want ta and none", cfg.Profiles[0].Token, cfg.Profiles[1].Token)
+	}
+	want := map[string]string{"a": "ta", "gone": "tg"}
+	if got := readSecretsFile(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("secrets file = %v, want %v", got, want)
+	}
+	if mode := fileMode(t, filepath.Join(dir, secretsFileName)); mode != 0600 {
+		t.Errorf("secrets file mode = %v, want 0600", mode)
+	}
+
+	// With nothing to move, the config file is left alone.
+	writeFile(t, path, "{}")
+	if err := cfg.moveTokens(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{}" {
+		t.Errorf("config file rewritten with no token to move:\n%s", data)
+	}
+}
+
+func TestRemoveProfileDeletesToken(t *testing.T) {
+	dir := isolate(t)
+	writeFile(t, filepath.Join(dir, configFileName), `{"profile": "a", "device_id": "d", "profiles": [{"name": "a", "server_url": "https://a.example", "token": "ta"}, {"name": "b", "server_url": "https://b.example", "token": "tb"}]}`)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RemoveProfile("a"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile != "" || cfg.Token != "" {
+		t.Errorf("removed profile still active: %q with token %q", cfg.Profile, cfg.Token)
+	}
+	want := map[string]string{"b": "tb"}
+	if got := readSecretsFile(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("secrets file = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// DefaultProfile names the profile of a login made before profiles existed.
+const DefaultProfile = "default"
+
+// Profile is a login on one server.
+type Profile struct {
+	Name      string `json:"name"`
+	ServerURL string `json:"server_url"`
+	Username  string `json:"username,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	// Token is kept in the keyring, never in the config file. It is
+	// loaded when the profile is first used.
+	Token string `json:"-"`
+}
+
+// Use makes the named profile the active one, loading its token first if
+// needed. A profile without a stored token is still used, and needs a new
+// login.
+func (c *Config) Use(name string) error {
+	i := slices.IndexFunc(c.Profiles, func(p Profile) bool { return p.Name == name })
+	if i < 0 {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	token := c.Profiles[i].Token
+	if token == "" {
+		var err error
+		if token, err = LoadToken(name); err != nil {
+			return err
+		}
+	}
+	return c.UseToken(name, token)
+}
+
+// UseToken makes the named profile the active one with a token read by
+// LoadToken. An empty token leaves the profile without a login.
+func (c *Config) UseToken(name, token string) error {
+	i := slices.IndexFunc(c.Profiles, func(p Profile) bool { return p.Name == name })
+	if i < 0 {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	p := &c.Profiles[i]
+	p.Token = token
+	c.Profile = p.Name
+	c.ServerURL = p.ServerURL
+	c.Username = p.Username
+	c.UserID = p.UserID
+	c.Token = p.Token
+	return nil
+}
+
+// NewProfile clears the active login, so that the next one saved becomes a
+// new profile.
+func (c *Config) NewProfile() {
+	c.Profile = ""
+	c.Username = ""
+	c.UserID = ""
+	c.Token = ""
+}
+
+// RemoveProfile forgets a profile along with its token and saved session,
+// and writes the config file. Removing the active profile leaves no login
+// active.
+func (c *Config) RemoveProfile(name string) error {
+	i := slices.IndexFunc(c.Profiles, func(p Profile) bool { return p.Name == name })
+	if i < 0 {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	if err := deleteToken(name); err != nil {
+		return err
+	}
+	if err := removeState(name); err != nil {
+		return err
+	}
+	c.Profiles = slices.Delete(c.Profiles, i, i+1)
+	if c.Profile == name {
+		c.NewProfile()
+	}
+	return SaveConfig(c)
+}
+
+// storeActive copies the active login into its profile, creating the
+// profile if needed. A new profile is named after the server host. It
+// reports whether the token of the profile changed.
+func (c *Config) storeActive() bool {
+	if c.ServerURL == "" || c.Token == "" {
+		return false
+	}
+	if c.Profile == "" {
+		c.Profile = c.uniqueName(profileName(c.ServerURL))
+	}
+	active := Profile{
+		Name:      c.Profile,
+		ServerURL: c.ServerURL,
+		Username:  c.Username,
+		UserID:    c.UserID,
+		Token:     c.Token,
+	}
+	for i, p := range c.Profiles {
+		if p.Name == c.Profile {
+			c.Profiles[i] = active
+			return p.Token != active.Token
+		}
+	}
+	c.Profiles = append(c.Profiles, active)
+	return true
+}
+
+func profileName(serverURL string) string {
+	if u, err := url.Parse(serverURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return DefaultProfile
+}
+
+func (c *Config) uniqueName(name string) string {
+	taken := func(n string) bool {
+		for _, p := range c.Profiles {
+			if p.Name == n {
+				return true
+			}
+		}
+		return false
+	}
+	unique := name
+	for i := 2; taken(unique); i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	return unique
+}
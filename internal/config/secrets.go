@@ -0,0 +1,160 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/keyring"
+)
+
+const (
+	// keyringService names the tokens in the keyring, one per profile.
+	keyringService = "jellyfin-mustui"
+	// secretsFileName holds the tokens when there is no keyring. Only the
+	// user can read it.
+	secretsFileName = "jellyfin-mustui-secrets.json"
+)
+
+// plainTokens holds the access tokens that older config files stored in
+// clear text.
+type plainTokens struct {
+	Token    string       `json:"token"`
+	Profiles []plainToken `json:"profiles"`
+}
+
+type plainToken struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// moveTokens stores tokens found in clear text in the config file away and
+// rewrites the file without them.
+func (c *Config) moveTokens(path string, tokens []plainToken) error {
+	moved := false
+	for _, t := range tokens {
+		if t.Token == "" {
+			continue
+		}
+		if err := saveToken(t.Name, t.Token); err != nil {
+			return err
+		}
+		for i := range c.Profiles {
+			if c.Profiles[i].Name == t.Name {
+				c.Profiles[i].Token = t.Token
+			}
+		}
+		moved = true
+	}
+	if !moved {
+		return nil
+	}
+	return writeConfig(path, c)
+}
+
+// LoadToken returns the token of a profile from the keyring, or else from
+// the secrets file, and "" when neither has one. A keyring that failed is
+// reported unless the secrets file has the token. It may wait for the
+// keyring to be unlocked; it touches no Config, so it can run in the
+// background.
+func LoadToken(profile string) (string, error) {
+	token, keyErr := keyring.Get(keyringService, profile)
+	if keyErr == nil {
+		return token, nil
+	}
+	tokens, err := readSecrets()
+	if err != nil {
+		return "", err
+	}
+	token = tokens[profile]
+	if token == "" && !errors.Is(keyErr, keyring.ErrNotFound) && !errors.Is(keyErr, keyring.ErrUnavailable) {
+		return "", keyErr
+	}
+	return token, nil
+}
+
+// saveToken stores the token of a profile in the keyring, or in the
+// secrets file when no keyring can be used.
+func saveToken(profile, token string) error {
+	if err := keyring.Set(keyringService, profile, token); err == nil {
+		// Drop the copy kept while there was no keyring.
+		return updateSecrets(profile, "")
+	}
+	return updateSecrets(profile, token)
+}
+
+// deleteToken removes the token of a profile from the keyring and the
+// secrets file.
+func deleteToken(profile string) error {
+	if err := keyring.Delete(keyringService, profile); err != nil && !errors.Is(err, keyring.ErrUnavailable) {
+		return err
+	}
+	return updateSecrets(profile, "")
+}
+
+func getSecretsPath() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), secretsFileName), nil
+}
+
+func readSecrets() (map[string]string, error) {
+	path, err := getSecretsPath()
+	if err != nil {
+		return nil, err
+	}
+	tokens := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// updateSecrets sets the token of a profile in the secrets file, removing
+// it when token is empty. The file is replaced atomically and created
+// readable by the user only.
+func updateSecrets(profile, token string) error {
+	tokens, err := readSecrets()
+	if err != nil {
+		return err
+	}
+	if old, ok := tokens[profile]; old == token && ok == (token != "") {
+		return nil
+	}
+	if token == "" {
+		delete(tokens, profile)
+	} else {
+		tokens[profile] = token
+	}
+
+	path, err := getSecretsPath()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return os.Remove(path)
+	}
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a file left over from an earlier run.
+	if err := os.Chmod(tmp, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
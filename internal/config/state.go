@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+const stateFileName = "jellyfin-mustui-state.json"
+
+// getStatePath returns the state file of a profile. The default profile
+// keeps the file name used before profiles existed.
+func getStatePath(profile string) (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	name := stateFileName
+	if profile != "" && profile != DefaultProfile {
+		name = fmt.Sprintf("jellyfin-mustui-state-%s.json", url.PathEscape(profile))
+	}
+	return filepath.Join(filepath.Dir(configPath), name), nil
+}
+
+// LoadState reads the saved session state of a profile into v. It leaves v
+// untouched and returns nil when no state has been saved yet.
+func LoadState(profile string, v interface{}) error {
+	path, err := getStatePath(profile)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// SaveState writes the session state of a profile next to the config file.
+// The file is replaced atomically so a crash mid-write never leaves a
+// truncated state.
+func SaveState(profile string, v interface{}) error {
+	path, err := getStatePath(profile)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// removeState deletes the saved session state of a profile, if any.
+func removeState(profile string) error {
+	path, err := getStatePath(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+)
+
+// requestTimeout bounds a whole request, including the time the server
+// takes to act on it.
+const requestTimeout = 15 * time.Second
+
+// Send delivers req to the server listening on path and returns its answer.
+// A response with an error message is returned as an error.
+func Send(path string, req Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, errors.New("jellyfin-mustui is not running (" + err.Error() + ")")
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
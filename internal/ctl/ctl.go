@@ -0,0 +1,154 @@
+// Package ctl is the local control protocol: newline-delimited JSON requests
+// and responses over a Unix domain socket.
+package ctl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+)
+
+// Commands understood by the server.
+const (
+	CmdPlayPause = "play-pause"
+	CmdPlay      = "play"
+	CmdPause     = "pause"
+	CmdStop      = "stop"
+	CmdNext      = "next"
+	CmdPrevious  = "previous"
+	CmdSeek      = "seek"
+	CmdVolume    = "volume"
+	CmdMute      = "mute"
+	CmdEnqueue   = "enqueue"
+	CmdStatus    = "status"
+
+	// Used by a TUI attached to a daemon.
+	CmdPlayTracks    = "play-tracks"
+	CmdPlayIndex     = "play-index"
+	CmdEnqueueTracks = "enqueue-tracks"
+	CmdMove          = "move"
+	CmdRemove        = "remove"
+	CmdClear         = "clear"
+	CmdShuffle       = "shuffle"
+	CmdRepeat        = "repeat"
+	CmdQueue         = "queue"
+)
+
+type Request struct {
+	Command string `json:"command"`
+	// Arg is the argument of seek, volume and enqueue, as typed on the
+	// command line.
+	Arg string `json:"arg,omitempty"`
+
+	// Tracks, Index and To carry the queue commands. Track URLs are not
+	// sent; the receiving side builds them.
+	Tracks []player.Track `json:"tracks,omitempty"`
+	Index  int            `json:"index,omitempty"`
+	To     int            `json:"to,omitempty"`
+}
+
+type Response struct {
+	OK     bool    `json:"ok"`
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+	// Queue is set in answer to the queue command.
+	Queue []player.Track `json:"queue,omitempty"`
+}
+
+// Handler answers a request. It is called from a separate goroutine for
+// each connection.
+type Handler func(Request) Response
+
+// Errorf builds a failed response.
+func Errorf(format string, args ...interface{}) Response {
+	return Response{Error: fmt.Sprintf(format, args...)}
+}
+
+// Status describes what the player is doing. Times are in seconds and the
+// volume is a percentage.
+type Status struct {
+	State    string  `json:"state"`
+	ItemID   string  `json:"item_id,omitempty"`
+	Title    string  `json:"title,omitempty"`
+	Artist   string  `json:"artist,omitempty"`
+	Album    string  `json:"album,omitempty"`
+	AlbumID  string  `json:"album_id,omitempty"`
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+	// Buffered is the downloaded fraction of the track, or -1 if unknown.
+	Buffered    float64 `json:"buffered"`
+	Volume      int     `json:"volume"`
+	Muted       bool    `json:"muted"`
+	Shuffle     bool    `json:"shuffle"`
+	Repeat      string  `json:"repeat"`
+	QueueIndex  int     `json:"queue_index"`
+	QueueLength int     `json:"queue_length"`
+}
+
+// StatusOf reports the current state of p.
+func StatusOf(p *player.Player) Status {
+	tracks, index := p.Queue()
+	s := Status{
+		State:       p.GetState().String(),
+		Position:    p.GetPosition().Seconds(),
+		Duration:    p.GetDuration().Seconds(),
+		Buffered:    p.Buffered(),
+		Volume:      int(p.Volume()*100 + 0.5),
+		Muted:       p.Muted(),
+		Shuffle:     p.Shuffle(),
+		Repeat:      p.Repeat().String(),
+		QueueIndex:  index,
+		QueueLength: len(tracks),
+	}
+	if t := p.GetCurrentTrack(); t != nil {
+		s.ItemID = t.ID
+		s.Title = t.Name
+		s.Artist = t.Artist
+		s.Album = t.Album
+		s.AlbumID = t.AlbumID
+	}
+	return s
+}
+
+// SocketPath returns where the control socket lives: in $XDG_RUNTIME_DIR
+// when set, otherwise in the temp directory with the user ID in the name.
+func SocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "jellyfin-mustui.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("jellyfin-mustui-%d.sock", os.Getuid()))
+}
+
+// ParseSeek reads a seek argument: "+10" and "-10" move relative to the
+// current position, "90" and "1:30" jump to a position.
+func ParseSeek(arg string) (d time.Duration, relative bool, err error) {
+	relative = strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-")
+	var seconds float64
+	if mins, secs, ok := strings.Cut(strings.TrimLeft(arg, "+-"), ":"); ok && !relative {
+		m, err1 := strconv.Atoi(mins)
+		s, err2 := strconv.ParseFloat(secs, 64)
+		if err1 != nil || err2 != nil {
+			return 0, false, fmt.Errorf("invalid position %q", arg)
+		}
+		seconds = float64(m)*60 + s
+	} else if seconds, err = strconv.ParseFloat(arg, 64); err != nil {
+		return 0, false, fmt.Errorf("invalid position %q", arg)
+	}
+	return time.Duration(seconds * float64(time.Second)), relative, nil
+}
+
+// ParseVolume reads a volume argument in percent: "+5" and "-5" change the
+// volume, "50" sets it. The result is a fraction.
+func ParseVolume(arg string) (level float64, relative bool, err error) {
+	relative = strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-")
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid volume %q", arg)
+	}
+	return percent / 100, relative, nil
+}
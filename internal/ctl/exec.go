@@ -0,0 +1,143 @@
+package ctl
+
+import (
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+)
+
+// Execute runs a request directly against p. Track URLs in req must already
+// be filled in. Enqueueing by item ID needs a library lookup and is left to
+// the caller.
+func Execute(p *player.Player, req Request) Response {
+	var err error
+	switch req.Command {
+	case CmdPlayPause:
+		if p.GetState() == player.StateStopped && p.GetCurrentTrack() != nil {
+			err = p.PlayFromQueue(p.GetQueueIndex())
+		} else {
+			p.TogglePause()
+		}
+	case CmdPlay:
+		switch p.GetState() {
+		case player.StatePaused:
+			p.Resume()
+		case player.StateStopped:
+			if p.GetCurrentTrack() != nil {
+				err = p.PlayFromQueue(p.GetQueueIndex())
+			}
+		}
+	case CmdPause:
+		p.Pause()
+	case CmdStop:
+		p.Stop()
+	case CmdNext:
+		err = p.Next()
+	case CmdPrevious:
+		err = p.Previous()
+	case CmdSeek:
+		d, relative, perr := ParseSeek(req.Arg)
+		switch {
+		case perr != nil:
+			err = perr
+		case relative:
+			err = p.SeekRelative(d)
+		default:
+			err = p.Seek(d)
+		}
+	case CmdVolume:
+		level, relative, perr := ParseVolume(req.Arg)
+		if perr != nil {
+			err = perr
+			break
+		}
+		if relative {
+			level += p.Volume()
+		}
+		p.SetVolume(level)
+	case CmdMute:
+		switch req.Arg {
+		case "":
+			p.ToggleMute()
+		case "on", "off":
+			if (req.Arg == "on") != p.Muted() {
+				p.ToggleMute()
+			}
+		default:
+			return Errorf("invalid mute argument %q", req.Arg)
+		}
+	case CmdPlayTracks:
+		err = p.PlayTracks(req.Tracks, req.Index)
+	case CmdPlayIndex:
+		err = p.PlayFromQueue(req.Index)
+	case CmdEnqueueTracks:
+		if req.Arg == "next" {
+			p.EnqueueNext(req.Tracks...)
+		} else {
+			p.Enqueue(req.Tracks...)
+		}
+	case CmdMove:
+		err = p.MoveInQueue(req.Index, req.To)
+	case CmdRemove:
+		err = p.RemoveFromQueue(req.Index)
+	case CmdClear:
+		p.ClearQueue()
+	case CmdShuffle:
+		switch req.Arg {
+		case "on":
+			p.SetShuffle(true)
+		case "off":
+			p.SetShuffle(false)
+		case "":
+			p.ToggleShuffle()
+		default:
+			return Errorf("invalid shuffle argument %q", req.Arg)
+		}
+	case CmdRepeat:
+		if req.Arg == "" {
+			p.CycleRepeat()
+			break
+		}
+		mode, ok := parseRepeat(req.Arg)
+		if !ok {
+			return Errorf("invalid repeat mode %q", req.Arg)
+		}
+		p.SetRepeat(mode)
+	case CmdQueue:
+		tracks, _ := p.Queue()
+		status := StatusOf(p)
+		return Response{OK: true, Status: &status, Queue: tracks}
+	case CmdStatus:
+		status := StatusOf(p)
+		return Response{OK: true, Status: &status}
+	default:
+		return Errorf("unknown command %q", req.Command)
+	}
+	if err != nil {
+		return Errorf("%v", err)
+	}
+	return Response{OK: true}
+}
+
+func parseRepeat(s string) (player.RepeatMode, bool) {
+	for _, mode := range []player.RepeatMode{player.RepeatOff, player.RepeatAll, player.RepeatOne} {
+		if mode.String() == s {
+			return mode, true
+		}
+	}
+	return player.RepeatOff, false
+}
+
+func parseState(s string) player.State {
+	for _, state := range []player.State{player.StatePlaying, player.StatePaused} {
+		if state.String() == s {
+			return state
+		}
+	}
+	return player.StateStopped
+}
+
+// seconds converts a duration in seconds as found in Status.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
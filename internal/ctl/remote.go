@@ -0,0 +1,257 @@
+package ctl
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+)
+
+// pollInterval is how often a Remote refreshes its copy of the status.
+const pollInterval = 250 * time.Millisecond
+
+// Remote drives a player running in another process, usually a daemon,
+// through its control socket. It offers the same methods as player.Player,
+// answering queries from a status copy that is refreshed in the background.
+// Every other method waits for the player to answer, so callers with an
+// interface to keep responsive should call them in the background.
+type Remote struct {
+	path string
+
+	mu     sync.Mutex
+	status Status
+	polled time.Time
+	// queue and queueIndex are the last queue fetched, returned when the
+	// player cannot be reached.
+	queue      []player.Track
+	queueIndex int
+
+	// wake asks the poller to refresh the status right away.
+	wake chan struct{}
+	quit chan struct{}
+	once sync.Once
+}
+
+func NewRemote(path string) *Remote {
+	return &Remote{
+		path:       path,
+		queueIndex: -1,
+		wake:       make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+	}
+}
+
+// Init checks that the player is reachable and starts polling its status.
+func (r *Remote) Init() error {
+	if err := r.refresh(); err != nil {
+		return err
+	}
+	go r.poll()
+	return nil
+}
+
+// Close stops polling. The remote player keeps running.
+func (r *Remote) Close() {
+	r.once.Do(func() { close(r.quit) })
+}
+
+func (r *Remote) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.quit:
+			return
+		case <-ticker.C:
+			r.refresh()
+		case <-r.wake:
+			r.refresh()
+		}
+	}
+}
+
+func (r *Remote) refresh() error {
+	resp, err := Send(r.path, Request{Command: CmdStatus})
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.status = *resp.Status
+	r.polled = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// call sends a command and has the status polled right away, so its effect
+// shows without waiting for the next poll.
+func (r *Remote) call(req Request) (*Response, error) {
+	resp, err := Send(r.path, req)
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return resp, err
+}
+
+func (r *Remote) snapshot() (Status, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status, r.polled
+}
+
+func (r *Remote) TogglePause() {
+	r.call(Request{Command: CmdPlayPause})
+}
+
+func (r *Remote) Next() error {
+	_, err := r.call(Request{Command: CmdNext})
+	return err
+}
+
+func (r *Remote) Previous() error {
+	_, err := r.call(Request{Command: CmdPrevious})
+	return err
+}
+
+func (r *Remote) Seek(pos time.Duration) error {
+	_, err := r.call(Request{Command: CmdSeek, Arg: strconv.FormatFloat(pos.Seconds(), 'f', -1, 64)})
+	return err
+}
+
+func (r *Remote) SeekRelative(delta time.Duration) error {
+	arg := strconv.FormatFloat(delta.Seconds(), 'f', -1, 64)
+	if delta >= 0 {
+		arg = "+" + arg
+	}
+	_, err := r.call(Request{Command: CmdSeek, Arg: arg})
+	return err
+}
+
+func (r *Remote) SetVolume(level float64) {
+	r.call(Request{Command: CmdVolume, Arg: strconv.FormatFloat(level*100, 'f', -1, 64)})
+}
+
+func (r *Remote) ToggleMute() bool {
+	r.call(Request{Command: CmdMute})
+	return r.Muted()
+}
+
+func (r *Remote) ToggleShuffle() bool {
+	r.call(Request{Command: CmdShuffle})
+	return r.Shuffle()
+}
+
+func (r *Remote) CycleRepeat() player.RepeatMode {
+	r.call(Request{Command: CmdRepeat})
+	return r.Repeat()
+}
+
+func (r *Remote) PlayTracks(tracks []player.Track, start int) error {
+	_, err := r.call(Request{Command: CmdPlayTracks, Tracks: tracks, Index: start})
+	return err
+}
+
+func (r *Remote) PlayFromQueue(index int) error {
+	_, err := r.call(Request{Command: CmdPlayIndex, Index: index})
+	return err
+}
+
+func (r *Remote) Enqueue(tracks ...player.Track) {
+	r.call(Request{Command: CmdEnqueueTracks, Tracks: tracks})
+}
+
+func (r *Remote) EnqueueNext(tracks ...player.Track) {
+	r.call(Request{Command: CmdEnqueueTracks, Arg: "next", Tracks: tracks})
+}
+
+func (r *Remote) MoveInQueue(from, to int) error {
+	_, err := r.call(Request{Command: CmdMove, Index: from, To: to})
+	return err
+}
+
+func (r *Remote) RemoveFromQueue(index int) error {
+	_, err := r.call(Request{Command: CmdRemove, Index: index})
+	return err
+}
+
+func (r *Remote) ClearQueue() {
+	r.call(Request{Command: CmdClear})
+}
+
+// Queue fetches the queue from the player. It returns the last queue
+// fetched if the player cannot be reached.
+func (r *Remote) Queue() ([]player.Track, int) {
+	resp, err := Send(r.path, Request{Command: CmdQueue})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.queue, r.queueIndex = resp.Queue, resp.Status.QueueIndex
+	}
+	return r.queue, r.queueIndex
+}
+
+func (r *Remote) GetState() player.State {
+	s, _ := r.snapshot()
+	return parseState(s.State)
+}
+
+// GetPosition extrapolates the last polled position while playing, so
+// progress moves smoothly between polls.
+func (r *Remote) GetPosition() time.Duration {
+	s, polled := r.snapshot()
+	pos := seconds(s.Position)
+	if parseState(s.State) == player.StatePlaying {
+		pos += time.Since(polled)
+	}
+	if dur := seconds(s.Duration); dur > 0 && pos > dur {
+		pos = dur
+	}
+	return pos
+}
+
+func (r *Remote) GetDuration() time.Duration {
+	s, _ := r.snapshot()
+	return seconds(s.Duration)
+}
+
+func (r *Remote) Buffered() float64 {
+	s, _ := r.snapshot()
+	return s.Buffered
+}
+
+func (r *Remote) GetCurrentTrack() *player.Track {
+	s, _ := r.snapshot()
+	if s.ItemID == "" {
+		return nil
+	}
+	return &player.Track{
+		ID:       s.ItemID,
+		Name:     s.Title,
+		Artist:   s.Artist,
+		Album:    s.Album,
+		AlbumID:  s.AlbumID,
+		Duration: seconds(s.Duration),
+	}
+}
+
+func (r *Remote) Volume() float64 {
+	s, _ := r.snapshot()
+	return float64(s.Volume) / 100
+}
+
+func (r *Remote) Muted() bool {
+	s, _ := r.snapshot()
+	return s.Muted
+}
+
+func (r *Remote) Shuffle() bool {
+	s, _ := r.snapshot()
+	return s.Shuffle
+}
+
+func (r *Remote) Repeat() player.RepeatMode {
+	s, _ := r.snapshot()
+	mode, _ := parseRepeat(s.Repeat)
+	return mode
+}
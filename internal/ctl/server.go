@@ -0,0 +1,93 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Server accepts control connections on a Unix domain socket.
+type Server struct {
+	path     string
+	listener net.Listener
+	handler  Handler
+
+	wg sync.WaitGroup
+}
+
+// Listen creates the socket at path and serves requests with handler until
+// Close is called. A stale socket left behind by a crashed process is
+// replaced; a live one is an error.
+func Listen(path string, handler Handler) (*Server, error) {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, errors.New("another instance is already listening on " + path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &Server{path: path, listener: listener, handler: handler}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle answers each request on conn until the client hangs up. A request
+// that is not valid JSON ends the connection, since the rest of the stream
+// cannot be read past it, but the client is told why first.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req Request
+		err := dec.Decode(&req)
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case err == io.EOF:
+			return
+		case errors.As(err, &typeErr):
+			if enc.Encode(Errorf("invalid request: %v", err)) != nil {
+				return
+			}
+			continue
+		case err != nil:
+			enc.Encode(Errorf("invalid request: %v", err))
+			return
+		}
+		if err := enc.Encode(s.handler(req)); err != nil {
+			return
+		}
+	}
+}
+
+// Close stops accepting connections and removes the socket.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	os.Remove(s.path)
+	return err
+}
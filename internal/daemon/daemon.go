@@ -0,0 +1,173 @@
+// Package daemon runs the player without a terminal. It is controlled
+// through the control socket, by `jellyfin-mustui ctl` or by a TUI started
+// with --attach.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/cast"
+	"github.com/cedev-1/jellyfin-mustui/internal/config"
+	"github.com/cedev-1/jellyfin-mustui/internal/ctl"
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/cedev-1/jellyfin-mustui/internal/mpris"
+	"github.com/cedev-1/jellyfin-mustui/internal/offline"
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	"github.com/cedev-1/jellyfin-mustui/internal/reporter"
+)
+
+// stateSaveInterval is how often the queue and position are saved.
+const stateSaveInterval = 30 * time.Second
+
+type daemon struct {
+	// ctx is cancelled on shutdown, abandoning requests still running.
+	ctx    context.Context
+	cfg    *config.Config
+	client *jellyfin.Client
+	player *player.Player
+	// restored is set once the previous session has been loaded. Until
+	// then the session is only saved once tracks were queued, so a failed
+	// restore never overwrites it with an empty queue.
+	restored bool
+}
+
+// Run plays until SIGINT or SIGTERM, then saves the session and returns.
+// Tracks in cache, which may be nil, are played from disk.
+func Run(cfg *config.Config, client *jellyfin.Client, cache *offline.Cache) error {
+	if cfg.Token == "" || cfg.ServerURL == "" || cfg.UserID == "" {
+		return errors.New("not logged in, run jellyfin-mustui once to log in")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := &daemon{ctx: ctx, cfg: cfg, client: client, player: player.New()}
+	if cache != nil {
+		d.player.LocalFile = cache.Path
+	}
+	if err := d.player.Init(); err != nil {
+		return err
+	}
+	defer d.player.Close()
+
+	rep := reporter.New(client)
+	defer rep.Close()
+	d.player.OnProgress = rep.Progress
+	d.player.OnTrackChange = rep.TrackChanged
+	d.player.OnStateChange = rep.StateChanged
+
+	if cfg.Volume != nil {
+		d.player.SetVolume(*cfg.Volume)
+	}
+	if err := d.restore(); err != nil {
+		log.Printf("Could not restore the previous session: %v", err)
+	} else {
+		d.restored = true
+	}
+
+	if server, err := mpris.Start(d.player, func(t player.Track) string {
+		if t.AlbumID == "" {
+			return ""
+		}
+		return client.GetImageURL(t.AlbumID)
+	}); err == nil {
+		defer server.Close()
+	}
+
+	path := ctl.SocketPath()
+	server, err := ctl.Listen(path, d.handle)
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening on %s", path)
+
+	remote := cast.Start(client, d.handle, func(err error) {
+		log.Printf("Remote control: %v", err)
+	})
+	defer remote.Close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(stateSaveInterval)
+	defer ticker.Stop()
+
+	for running := true; running; {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %s, shutting down", sig)
+			running = false
+		case <-ticker.C:
+			if err := d.save(); err != nil {
+				log.Printf("Could not save the session: %v", err)
+			}
+		}
+	}
+
+	server.Close()
+	if err := d.save(); err != nil {
+		log.Printf("Could not save the session: %v", err)
+	}
+	volume := d.player.Volume()
+	cfg.Volume = &volume
+	return config.SaveConfig(cfg)
+}
+
+func (d *daemon) handle(req ctl.Request) ctl.Response {
+	if req.Command == ctl.CmdEnqueue {
+		if req.Arg == "" {
+			return ctl.Errorf("enqueue needs an item ID")
+		}
+		items, err := d.client.GetItemTracks(d.ctx, req.Arg)
+		if err != nil {
+			return ctl.Errorf("%v", err)
+		}
+		tracks := make([]player.Track, len(items))
+		for i, item := range items {
+			tracks[i] = d.track(item)
+		}
+		d.player.Enqueue(tracks...)
+		return ctl.Response{OK: true}
+	}
+
+	for i := range req.Tracks {
+		req.Tracks[i].URL = d.client.GetAudioStreamURL(req.Tracks[i].ID, player.SupportedContainers)
+	}
+	return ctl.Execute(d.player, req)
+}
+
+func (d *daemon) track(item jellyfin.MusicItem) player.Track {
+	return player.Track{
+		ID:       item.ID,
+		Name:     item.Name,
+		Artist:   item.AlbumArtist,
+		Album:    item.Album,
+		AlbumID:  item.AlbumID,
+		Duration: time.Duration(item.RunTimeTicks/10000000) * time.Second,
+		URL:      d.client.GetAudioStreamURL(item.ID, player.SupportedContainers),
+	}
+}
+
+// restore loads the queue saved by the previous run, paused.
+func (d *daemon) restore() error {
+	snap := d.player.Snapshot()
+	if err := config.LoadState(d.cfg.Profile, &snap); err != nil {
+		return err
+	}
+	for i := range snap.Queue {
+		snap.Queue[i].URL = d.client.GetAudioStreamURL(snap.Queue[i].ID, player.SupportedContainers)
+	}
+	return d.player.Restore(snap)
+}
+
+func (d *daemon) save() error {
+	snap := d.player.Snapshot()
+	if !d.restored && len(snap.Queue) == 0 {
+		return nil
+	}
+	return config.SaveState(d.cfg.Profile, snap)
+}
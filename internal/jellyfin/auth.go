@@ -0,0 +1,135 @@
+package jellyfin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// QuickConnect is a pending Quick Connect request. The user approves Code
+// from a logged-in Jellyfin client; Secret identifies the request to the
+// server.
+type QuickConnect struct {
+	Authenticated bool   `json:"Authenticated"`
+	Secret        string `json:"Secret"`
+	Code          string `json:"Code"`
+}
+
+// InitiateQuickConnect starts a Quick Connect request.
+func (c *Client) InitiateQuickConnect(ctx context.Context) (*QuickConnect, error) {
+	endpoint := fmt.Sprintf("%s/QuickConnect/Initiate", c.ServerURL)
+	// Servers before 10.9 only accept GET here.
+	resp, err := c.quickConnect(ctx, "POST", endpoint, nil)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		resp, err = c.quickConnect(ctx, "GET", endpoint, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("quick connect is disabled on this server")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to start quick connect: %s", resp.Status)
+	}
+
+	var qc QuickConnect
+	if err := json.NewDecoder(resp.Body).Decode(&qc); err != nil {
+		return nil, err
+	}
+	return &qc, nil
+}
+
+// QuickConnectState polls a Quick Connect request.
+func (c *Client) QuickConnectState(ctx context.Context, secret string) (*QuickConnect, error) {
+	endpoint := fmt.Sprintf("%s/QuickConnect/Connect?Secret=%s", c.ServerURL, url.QueryEscape(secret))
+	resp, err := c.quickConnect(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("quick connect request expired: %s", resp.Status)
+	}
+
+	var qc QuickConnect
+	if err := json.NewDecoder(resp.Body).Decode(&qc); err != nil {
+		return nil, err
+	}
+	return &qc, nil
+}
+
+// AuthenticateWithQuickConnect logs in with an approved Quick Connect
+// request.
+func (c *Client) AuthenticateWithQuickConnect(ctx context.Context, secret string) (*AuthResponse, error) {
+	endpoint := fmt.Sprintf("%s/Users/AuthenticateWithQuickConnect", c.ServerURL)
+	body, _ := json.Marshal(map[string]string{"Secret": secret})
+	resp, err := c.quickConnect(ctx, "POST", endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("quick connect authentication failed: %s", resp.Status)
+	}
+
+	var authResp AuthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+		return nil, err
+	}
+
+	c.Token = authResp.AccessToken
+	c.UserID = authResp.User.ID
+	return &authResp, nil
+}
+
+func (c *Client) quickConnect(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.addHeaders(req)
+	return c.do(req)
+}
+
+// AuthenticateWithAPIKey logs in with an existing access token or API key,
+// checking it against the user it belongs to. The client keeps its
+// credentials unless the key is accepted.
+func (c *Client) AuthenticateWithAPIKey(ctx context.Context, key string) (*AuthResponse, error) {
+	endpoint := fmt.Sprintf("%s/Users/Me", c.ServerURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.addHeadersWith(req, key)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid API key: %s", resp.Status)
+	}
+
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, err
+	}
+
+	c.Token = key
+	c.UserID = user.ID
+	return &AuthResponse{User: user, AccessToken: key}, nil
+}
@@ -0,0 +1,103 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GetAllAlbums lists every album in the library, by name.
+func (c *Client) GetAllAlbums(ctx context.Context) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.allAlbumsEndpoint(), "albums")
+}
+
+func (c *Client) GetAllAlbumsPage(ctx context.Context, page Page) (*MusicItemsResponse, error) {
+	return c.getMusicItemsPage(ctx, c.allAlbumsEndpoint(), "albums", page)
+}
+
+func (c *Client) allAlbumsEndpoint() string {
+	return fmt.Sprintf("%s/Users/%s/Items?IncludeItemTypes=MusicAlbum&Recursive=true&SortBy=SortName&SortOrder=Ascending&EnableUserData=true",
+		c.ServerURL, c.UserID)
+}
+
+// GetGenres lists the music genres.
+func (c *Client) GetGenres(ctx context.Context) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.genresEndpoint(), "genres")
+}
+
+func (c *Client) GetGenresPage(ctx context.Context, page Page) (*MusicItemsResponse, error) {
+	return c.getMusicItemsPage(ctx, c.genresEndpoint(), "genres", page)
+}
+
+func (c *Client) genresEndpoint() string {
+	return fmt.Sprintf("%s/MusicGenres?UserId=%s&SortBy=SortName&SortOrder=Ascending&EnableUserData=true",
+		c.ServerURL, c.UserID)
+}
+
+// GetAlbumsByGenre lists the albums of a genre, by name.
+func (c *Client) GetAlbumsByGenre(ctx context.Context, genreID string) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.genreAlbumsEndpoint(genreID), "albums")
+}
+
+func (c *Client) genreAlbumsEndpoint(genreID string) string {
+	return fmt.Sprintf("%s/Users/%s/Items?IncludeItemTypes=MusicAlbum&Recursive=true&GenreIds=%s&SortBy=SortName&SortOrder=Ascending&EnableUserData=true",
+		c.ServerURL, c.UserID, genreID)
+}
+
+// GetAlbumYears returns the years albums were released in, newest first.
+func (c *Client) GetAlbumYears(ctx context.Context) ([]int, error) {
+	items, err := c.getMusicItems(ctx, fmt.Sprintf("%s/Years?UserId=%s&IncludeItemTypes=MusicAlbum&Recursive=true",
+		c.ServerURL, c.UserID), "years")
+	if err != nil {
+		return nil, err
+	}
+	years := make([]int, 0, len(items))
+	for _, item := range items {
+		if year, err := strconv.Atoi(item.Name); err == nil {
+			years = append(years, year)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+	return years, nil
+}
+
+// GetAlbumsByYears lists the albums released in any of years, oldest first.
+func (c *Client) GetAlbumsByYears(ctx context.Context, years []int) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.yearAlbumsEndpoint(years), "albums")
+}
+
+func (c *Client) yearAlbumsEndpoint(years []int) string {
+	list := make([]string, len(years))
+	for i, year := range years {
+		list[i] = strconv.Itoa(year)
+	}
+	return fmt.Sprintf("%s/Users/%s/Items?IncludeItemTypes=MusicAlbum&Recursive=true&Years=%s&SortBy=ProductionYear,SortName&SortOrder=Ascending&EnableUserData=true",
+		c.ServerURL, c.UserID, strings.Join(list, ","))
+}
+
+// GetComposers lists the people credited as composer on any track.
+func (c *Client) GetComposers(ctx context.Context) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.composersEndpoint(), "composers")
+}
+
+func (c *Client) GetComposersPage(ctx context.Context, page Page) (*MusicItemsResponse, error) {
+	return c.getMusicItemsPage(ctx, c.composersEndpoint(), "composers", page)
+}
+
+func (c *Client) composersEndpoint() string {
+	return fmt.Sprintf("%s/Persons?UserId=%s&PersonTypes=Composer&EnableUserData=true",
+		c.ServerURL, c.UserID)
+}
+
+// GetTracksByComposer lists the tracks credited to a composer, album by
+// album.
+func (c *Client) GetTracksByComposer(ctx context.Context, personID string) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.composerTracksEndpoint(personID), "tracks")
+}
+
+func (c *Client) composerTracksEndpoint(personID string) string {
+	return fmt.Sprintf("%s/Users/%s/Items?IncludeItemTypes=Audio&Recursive=true&PersonIds=%s&PersonTypes=Composer&SortBy=Album,ParentIndexNumber,IndexNumber&SortOrder=Ascending&EnableUserData=true",
+		c.ServerURL, c.UserID, personID)
+}
@@ -0,0 +1,20 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// GetChangedItems lists the artists, albums and tracks saved on the server
+// since the given time. With userData set it lists those whose user data,
+// such as the favorite flag, changed instead. Removed items are not listed.
+func (c *Client) GetChangedItems(ctx context.Context, since time.Time, userData bool) ([]MusicItem, error) {
+	param := "MinDateLastSaved"
+	if userData {
+		param = "MinDateLastSavedForUser"
+	}
+	return c.getMusicItems(ctx, fmt.Sprintf("%s/Users/%s/Items?IncludeItemTypes=MusicArtist,MusicAlbum,Audio&Recursive=true&EnableUserData=true&%s=%s",
+		c.ServerURL, c.UserID, param, url.QueryEscape(since.UTC().Format(time.RFC3339))), "changes")
+}
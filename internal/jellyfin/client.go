@@ -2,37 +2,52 @@ package jellyfin
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 type Client struct {
 	ServerURL  string
 	Token      string
 	UserID     string
+	Device     Device
 	HTTPClient *http.Client
+	// Timeout limits each attempt at a request to the server; zero means
+	// no limit. Downloads are only limited by their context.
+	Timeout time.Duration
+	// Retries is how many times a failed GET is tried again.
+	Retries int
 }
 
-func NewClient(serverURL, token, userID string) *Client {
+func NewClient(serverURL, token, userID string, device Device) *Client {
 	return &Client{
 		ServerURL:  serverURL,
 		Token:      token,
 		UserID:     userID,
+		Device:     device,
 		HTTPClient: &http.Client{},
+		Timeout:    DefaultTimeout,
+		Retries:    DefaultRetries,
 	}
 }
 
+type User struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
 type AuthResponse struct {
-	User struct {
-		ID   string `json:"Id"`
-		Name string `json:"Name"`
-	} `json:"User"`
+	User        User   `json:"User"`
 	AccessToken string `json:"AccessToken"`
 }
 
-func (c *Client) Authenticate(username, password string) (*AuthResponse, error) {
+func (c *Client) Authenticate(ctx context.Context, username, password string) (*AuthResponse, error) {
 	endpoint := fmt.Sprintf("%s/Users/AuthenticateByName", c.ServerURL)
 	payload := map[string]string{
 		"Username": username,
@@ -40,14 +55,14 @@ func (c *Client) Authenticate(username, password string) (*AuthResponse, error)
 	}
 	body, _ := json.Marshal(payload)
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Emby-Authorization", "MediaBrowser Client=\"Jellyfin-TUI\", Device=\"Terminal\", DeviceId=\"TODO-ID\", Version=\"0.0.1\"")
+	c.addHeaders(req)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -75,22 +90,24 @@ type Item struct {
 }
 
 type ItemsResponse struct {
-	Items []Item `json:"Items"`
+	Items            []Item `json:"Items"`
+	TotalRecordCount int    `json:"TotalRecordCount"`
+	StartIndex       int    `json:"StartIndex"`
 }
 
-func (c *Client) GetViews() ([]Item, error) {
+func (c *Client) GetViews(ctx context.Context) ([]Item, error) {
 	if c.Token == "" || c.UserID == "" {
 		return nil, fmt.Errorf("not authenticated")
 	}
 
 	endpoint := fmt.Sprintf("%s/Users/%s/Views", c.ServerURL, c.UserID)
-	req, err := http.NewRequest("GET", endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 	c.addHeaders(req)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -108,105 +125,121 @@ func (c *Client) GetViews() ([]Item, error) {
 	return itemsResp.Items, nil
 }
 
-func (c *Client) GetItems(parentID string) ([]Item, error) {
-	if c.Token == "" || c.UserID == "" {
-		return nil, fmt.Errorf("not authenticated")
-	}
-
-	endpoint := fmt.Sprintf("%s/Users/%s/Items?ParentId=%s", c.ServerURL, c.UserID, parentID)
-	req, err := http.NewRequest("GET", endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-	c.addHeaders(req)
-
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get items: %s", resp.Status)
-	}
+func (c *Client) GetItems(ctx context.Context, parentID string) ([]Item, error) {
+	return getAll(func(page Page) ([]Item, int, error) {
+		itemsResp, err := c.GetItemsPage(ctx, parentID, page)
+		if err != nil {
+			return nil, 0, err
+		}
+		return itemsResp.Items, itemsResp.TotalRecordCount, nil
+	})
+}
 
+func (c *Client) GetItemsPage(ctx context.Context, parentID string, page Page) (*ItemsResponse, error) {
 	var itemsResp ItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
+	endpoint := fmt.Sprintf("%s/Users/%s/Items?ParentId=%s", c.ServerURL, c.UserID, parentID)
+	if err := c.getPage(ctx, endpoint, "items", page, &itemsResp); err != nil {
 		return nil, err
 	}
-
-	return itemsResp.Items, nil
+	return &itemsResp, nil
 }
 
 func (c *Client) GetImageURL(itemID string) string {
 	return fmt.Sprintf("%s/Items/%s/Images/Primary", c.ServerURL, itemID)
 }
 
+type NameID struct {
+	Name string `json:"Name"`
+	ID   string `json:"Id"`
+}
+
+// UserData is the per-user state of an item.
+type UserData struct {
+	IsFavorite     bool   `json:"IsFavorite"`
+	PlayCount      int    `json:"PlayCount"`
+	LastPlayedDate string `json:"LastPlayedDate,omitempty"`
+}
+
 type MusicItem struct {
-	ID           string `json:"Id"`
-	Name         string `json:"Name"`
-	Type         string `json:"Type"`
-	AlbumArtist  string `json:"AlbumArtist"`
-	Album        string `json:"Album"`
-	RunTimeTicks int64  `json:"RunTimeTicks"`
-	IndexNumber  int    `json:"IndexNumber"`
+	ID           string   `json:"Id"`
+	Name         string   `json:"Name"`
+	Type         string   `json:"Type"`
+	AlbumArtist  string   `json:"AlbumArtist"`
+	AlbumArtists []NameID `json:"AlbumArtists"`
+	Album        string   `json:"Album"`
+	AlbumID      string   `json:"AlbumId"`
+	RunTimeTicks int64    `json:"RunTimeTicks"`
+	IndexNumber  int      `json:"IndexNumber"`
+	UserData     UserData `json:"UserData"`
+	// PlaylistItemID identifies the entry when the item was listed as part
+	// of a playlist.
+	PlaylistItemID string `json:"PlaylistItemId,omitempty"`
 }
 
+// MusicItemsResponse is a page of a list. TotalRecordCount is the length
+// of the whole list.
 type MusicItemsResponse struct {
-	Items []MusicItem `json:"Items"`
+	Items            []MusicItem `json:"Items"`
+	TotalRecordCount int         `json:"TotalRecordCount"`
+	StartIndex       int         `json:"StartIndex"`
 }
 
-func (c *Client) GetArtists() ([]MusicItem, error) {
+func (c *Client) GetItem(ctx context.Context, itemID string) (*MusicItem, error) {
 	if c.Token == "" || c.UserID == "" {
 		return nil, fmt.Errorf("not authenticated")
 	}
 
-	endpoint := fmt.Sprintf("%s/Artists?UserId=%s&SortBy=SortName&SortOrder=Ascending", c.ServerURL, c.UserID)
-	req, err := http.NewRequest("GET", endpoint, nil)
+	endpoint := fmt.Sprintf("%s/Users/%s/Items/%s", c.ServerURL, c.UserID, itemID)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 	c.addHeaders(req)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get artists: %s", resp.Status)
+		return nil, fmt.Errorf("failed to get item: %s", resp.Status)
 	}
 
-	var itemsResp MusicItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
+	var item MusicItem
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
 		return nil, err
 	}
 
-	return itemsResp.Items, nil
+	return &item, nil
 }
 
-func (c *Client) GetAlbums(artistID string) ([]MusicItem, error) {
+// GetItemsByID looks up several items at once, in the order of ids. IDs
+// the server does not know are left out.
+func (c *Client) GetItemsByID(ctx context.Context, ids []string) ([]MusicItem, error) {
 	if c.Token == "" || c.UserID == "" {
 		return nil, fmt.Errorf("not authenticated")
 	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
 
-	endpoint := fmt.Sprintf("%s/Users/%s/Items?ArtistIds=%s&IncludeItemTypes=MusicAlbum&Recursive=true&SortBy=SortName",
-		c.ServerURL, c.UserID, artistID)
-	req, err := http.NewRequest("GET", endpoint, nil)
+	endpoint := fmt.Sprintf("%s/Users/%s/Items?Ids=%s&EnableUserData=true",
+		c.ServerURL, c.UserID, url.QueryEscape(strings.Join(ids, ",")))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 	c.addHeaders(req)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get albums: %s", resp.Status)
+		return nil, fmt.Errorf("failed to get items: %s", resp.Status)
 	}
 
 	var itemsResp MusicItemsResponse
@@ -214,77 +247,193 @@ func (c *Client) GetAlbums(artistID string) ([]MusicItem, error) {
 		return nil, err
 	}
 
-	return itemsResp.Items, nil
+	byID := make(map[string]MusicItem, len(itemsResp.Items))
+	for _, item := range itemsResp.Items {
+		byID[item.ID] = item
+	}
+	items := make([]MusicItem, 0, len(ids))
+	for _, id := range ids {
+		if item, ok := byID[id]; ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// GetItemTracks resolves an item to the tracks it stands for: a track on
+// its own, or every track of an album, playlist or artist.
+func (c *Client) GetItemTracks(ctx context.Context, itemID string) ([]MusicItem, error) {
+	item, err := c.GetItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	switch item.Type {
+	case "Audio":
+		return []MusicItem{*item}, nil
+	case "MusicAlbum":
+		return c.GetTracks(ctx, item.ID)
+	case "Playlist":
+		return c.GetPlaylistItems(ctx, item.ID)
+	case "MusicArtist":
+		return c.GetTracksByArtist(ctx, item.ID)
+	}
+	return nil, fmt.Errorf("cannot play a %s", item.Type)
+}
+
+func (c *Client) GetArtists(ctx context.Context) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.artistsEndpoint(), "artists")
+}
+
+func (c *Client) GetArtistsPage(ctx context.Context, page Page) (*MusicItemsResponse, error) {
+	return c.getMusicItemsPage(ctx, c.artistsEndpoint(), "artists", page)
+}
+
+func (c *Client) artistsEndpoint() string {
+	return fmt.Sprintf("%s/Artists?UserId=%s&SortBy=SortName&SortOrder=Ascending&EnableUserData=true", c.ServerURL, c.UserID)
+}
+
+func (c *Client) GetAlbums(ctx context.Context, artistID string) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.albumsEndpoint(artistID), "albums")
+}
+
+func (c *Client) albumsEndpoint(artistID string) string {
+	return fmt.Sprintf("%s/Users/%s/Items?ArtistIds=%s&IncludeItemTypes=MusicAlbum&Recursive=true&SortBy=SortName&EnableUserData=true",
+		c.ServerURL, c.UserID, artistID)
 }
 
-func (c *Client) GetTracksByArtist(artistID string) ([]MusicItem, error) {
+func (c *Client) GetTracksByArtist(ctx context.Context, artistID string) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.artistTracksEndpoint(artistID), "tracks")
+}
+
+func (c *Client) artistTracksEndpoint(artistID string) string {
+	return fmt.Sprintf("%s/Users/%s/Items?ArtistIds=%s&IncludeItemTypes=Audio&Recursive=true&SortBy=Album,IndexNumber&EnableUserData=true",
+		c.ServerURL, c.UserID, artistID)
+}
+
+func (c *Client) GetTracks(ctx context.Context, albumID string) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.tracksEndpoint(albumID), "tracks")
+}
+
+func (c *Client) tracksEndpoint(albumID string) string {
+	return fmt.Sprintf("%s/Users/%s/Items?ParentId=%s&IncludeItemTypes=Audio&SortBy=IndexNumber&EnableUserData=true",
+		c.ServerURL, c.UserID, albumID)
+}
+
+type SearchResults struct {
+	Artists []MusicItem
+	Albums  []MusicItem
+	Tracks  []MusicItem
+}
+
+// searchLimit caps the results returned for each kind of item.
+const searchLimit = 25
+
+// Search looks up artists, albums and tracks across the whole library.
+func (c *Client) Search(ctx context.Context, term string) (*SearchResults, error) {
 	if c.Token == "" || c.UserID == "" {
 		return nil, fmt.Errorf("not authenticated")
 	}
 
-	endpoint := fmt.Sprintf("%s/Users/%s/Items?ArtistIds=%s&IncludeItemTypes=Audio&Recursive=true&SortBy=Album,IndexNumber",
-		c.ServerURL, c.UserID, artistID)
-	req, err := http.NewRequest("GET", endpoint, nil)
+	artists, err := c.searchItems(ctx, fmt.Sprintf("%s/Artists?UserId=%s&SearchTerm=%s",
+		c.ServerURL, c.UserID, url.QueryEscape(term)))
 	if err != nil {
 		return nil, err
 	}
-	c.addHeaders(req)
 
-	resp, err := c.HTTPClient.Do(req)
+	albums, err := c.searchItems(ctx, fmt.Sprintf("%s/Users/%s/Items?SearchTerm=%s&IncludeItemTypes=MusicAlbum&Recursive=true",
+		c.ServerURL, c.UserID, url.QueryEscape(term)))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get tracks: %s", resp.Status)
+	tracks, err := c.searchItems(ctx, fmt.Sprintf("%s/Users/%s/Items?SearchTerm=%s&IncludeItemTypes=Audio&Recursive=true",
+		c.ServerURL, c.UserID, url.QueryEscape(term)))
+	if err != nil {
+		return nil, err
 	}
 
-	var itemsResp MusicItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
+	return &SearchResults{Artists: artists, Albums: albums, Tracks: tracks}, nil
+}
+
+func (c *Client) searchItems(ctx context.Context, endpoint string) ([]MusicItem, error) {
+	itemsResp, err := c.getMusicItemsPage(ctx, endpoint, "search results", Page{Limit: searchLimit})
+	if err != nil {
 		return nil, err
 	}
-
 	return itemsResp.Items, nil
 }
 
-func (c *Client) GetTracks(albumID string) ([]MusicItem, error) {
+// GetAudioStreamURL returns a universal stream URL for the item. The server
+// sends the original file when its format is listed in containers and
+// transcodes to MP3 otherwise.
+func (c *Client) GetAudioStreamURL(itemID string, containers []string) string {
+	params := url.Values{}
+	params.Set("UserId", c.UserID)
+	params.Set("api_key", c.Token)
+	params.Set("Container", strings.Join(containers, ","))
+	params.Set("TranscodingContainer", "mp3")
+	params.Set("TranscodingProtocol", "http")
+	params.Set("AudioCodec", "mp3")
+	return fmt.Sprintf("%s/Audio/%s/universal?%s", c.ServerURL, itemID, params.Encode())
+}
+
+type PlaybackInfo struct {
+	ItemID        string `json:"ItemId"`
+	PositionTicks int64  `json:"PositionTicks"`
+	IsPaused      bool   `json:"IsPaused"`
+	CanSeek       bool   `json:"CanSeek"`
+	PlayMethod    string `json:"PlayMethod,omitempty"`
+	PlaySessionID string `json:"PlaySessionId,omitempty"`
+	EventName     string `json:"EventName,omitempty"`
+}
+
+// DurationToTicks converts a duration to Jellyfin ticks (100ns units).
+func DurationToTicks(d time.Duration) int64 {
+	return int64(d / 100)
+}
+
+// TicksToDuration converts Jellyfin ticks to a duration.
+func TicksToDuration(ticks int64) time.Duration {
+	return time.Duration(ticks) * 100
+}
+
+func (c *Client) ReportPlaybackStart(ctx context.Context, info PlaybackInfo) error {
+	return c.postSession(ctx, "/Sessions/Playing", info)
+}
+
+func (c *Client) ReportPlaybackProgress(ctx context.Context, info PlaybackInfo) error {
+	return c.postSession(ctx, "/Sessions/Playing/Progress", info)
+}
+
+func (c *Client) ReportPlaybackStopped(ctx context.Context, info PlaybackInfo) error {
+	return c.postSession(ctx, "/Sessions/Playing/Stopped", info)
+}
+
+func (c *Client) postSession(ctx context.Context, path string, info PlaybackInfo) error {
 	if c.Token == "" || c.UserID == "" {
-		return nil, fmt.Errorf("not authenticated")
+		return fmt.Errorf("not authenticated")
 	}
 
-	endpoint := fmt.Sprintf("%s/Users/%s/Items?ParentId=%s&IncludeItemTypes=Audio&SortBy=IndexNumber",
-		c.ServerURL, c.UserID, albumID)
-	req, err := http.NewRequest("GET", endpoint, nil)
+	body, err := json.Marshal(info)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	c.addHeaders(req)
 
-	resp, err := c.HTTPClient.Do(req)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.ServerURL+path, bytes.NewBuffer(body))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
+	req.Header.Set("Content-Type", "application/json")
+	c.addHeaders(req)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get tracks: %s", resp.Status)
+	resp, err := c.do(req)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
-	var itemsResp MusicItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to report playback: %s", resp.Status)
 	}
-
-	return itemsResp.Items, nil
-}
-
-func (c *Client) GetAudioStreamURL(itemID string) string {
-	return fmt.Sprintf("%s/Audio/%s/universal?UserId=%s&api_key=%s&Container=mp3&AudioCodec=mp3",
-		c.ServerURL, itemID, c.UserID, c.Token)
-}
-
-func (c *Client) addHeaders(req *http.Request) {
-	req.Header.Set("X-Emby-Token", c.Token)
-	req.Header.Set("X-Emby-Authorization", "MediaBrowser Client=\"Jellyfin-TUI\", Device=\"Terminal\", DeviceId=\"TODO-ID\", Version=\"0.0.1\", Token=\""+c.Token+"\"")
+	return nil
 }
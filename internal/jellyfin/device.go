@@ -0,0 +1,80 @@
+package jellyfin
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// clientName is how the server lists this client in its sessions.
+const clientName = "Jellyfin-TUI"
+
+// version is the version of this build, as reported to the server.
+var version = buildVersion()
+
+// Device identifies this install to the server. The server keeps one
+// session and access token per device and user, so each install needs its
+// own ID.
+type Device struct {
+	ID string
+	// Name is shown in the server's device list. Empty means the host name.
+	Name string
+}
+
+func (d Device) name() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "Terminal"
+}
+
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return strings.TrimPrefix(v, "v")
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 7 {
+			return "dev-" + s.Value[:7]
+		}
+	}
+	return "dev"
+}
+
+// authorization identifies the client to the server, with the token once
+// logged in. Values are URL-encoded, which the server decodes.
+func (c *Client) authorization() string {
+	return c.authorizationWith(c.Token)
+}
+
+func (c *Client) authorizationWith(token string) string {
+	auth := fmt.Sprintf("MediaBrowser Client=%q, Device=%q, DeviceId=%q, Version=%q",
+		clientName, url.QueryEscape(c.Device.name()), url.QueryEscape(c.Device.ID), version)
+	if token != "" {
+		auth += fmt.Sprintf(", Token=%q", token)
+	}
+	return auth
+}
+
+// addHeaders sets the headers every request to the server carries.
+func (c *Client) addHeaders(req *http.Request) {
+	c.addHeadersWith(req, c.Token)
+}
+
+// addHeadersWith sets the headers of a request made with token instead of
+// the token of the client.
+func (c *Client) addHeadersWith(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("X-Emby-Token", token)
+	}
+	req.Header.Set("X-Emby-Authorization", c.authorizationWith(token))
+}
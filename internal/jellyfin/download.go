@@ -0,0 +1,36 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Download opens the original file of an item, as stored on the server. The
+// caller must close the returned body. The client timeout does not apply, as
+// reading a large file takes time; cancelling ctx stops the download.
+func (c *Client) Download(ctx context.Context, itemID string) (io.ReadCloser, error) {
+	if c.Token == "" || c.UserID == "" {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	endpoint := fmt.Sprintf("%s/Items/%s/Download", c.ServerURL, itemID)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.addHeaders(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download item: %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,54 @@
+package jellyfin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetFavorites returns the user's favorite items of one type, such as
+// "Audio", "MusicAlbum" or "MusicArtist".
+func (c *Client) GetFavorites(ctx context.Context, itemType string) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.favoritesEndpoint(itemType), "favorites")
+}
+
+func (c *Client) favoritesEndpoint(itemType string) string {
+	sortBy := "SortName"
+	if itemType == "Audio" {
+		sortBy = "AlbumArtist,Album,IndexNumber"
+	}
+	return fmt.Sprintf("%s/Users/%s/Items?Filters=IsFavorite&IncludeItemTypes=%s&Recursive=true&SortBy=%s&EnableUserData=true",
+		c.ServerURL, c.UserID, itemType, sortBy)
+}
+
+func (c *Client) SetFavorite(ctx context.Context, itemID string) error {
+	return c.markFavorite(ctx, "POST", itemID)
+}
+
+func (c *Client) UnsetFavorite(ctx context.Context, itemID string) error {
+	return c.markFavorite(ctx, "DELETE", itemID)
+}
+
+func (c *Client) markFavorite(ctx context.Context, method, itemID string) error {
+	if c.Token == "" || c.UserID == "" {
+		return fmt.Errorf("not authenticated")
+	}
+
+	endpoint := fmt.Sprintf("%s/Users/%s/FavoriteItems/%s", c.ServerURL, c.UserID, itemID)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	c.addHeaders(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update favorite: %s", resp.Status)
+	}
+	return nil
+}
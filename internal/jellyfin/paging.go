@@ -0,0 +1,92 @@
+package jellyfin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// pageSize is how many items each request asks for when a whole list is
+// fetched.
+const pageSize = 500
+
+// Page selects part of a list: Limit items from StartIndex on. The zero
+// Page is the whole list.
+type Page struct {
+	StartIndex int
+	Limit      int
+}
+
+func (p Page) query() string {
+	var q string
+	if p.StartIndex > 0 {
+		q += fmt.Sprintf("&StartIndex=%d", p.StartIndex)
+	}
+	if p.Limit > 0 {
+		q += fmt.Sprintf("&Limit=%d", p.Limit)
+	}
+	return q
+}
+
+// getPage fetches one page of a list endpoint into v. what names the items
+// in errors.
+func (c *Client) getPage(ctx context.Context, endpoint, what string, page Page, v any) error {
+	if c.Token == "" || c.UserID == "" {
+		return fmt.Errorf("not authenticated")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+page.query(), nil)
+	if err != nil {
+		return err
+	}
+	c.addHeaders(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: %s", what, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// getAll fetches a whole list, a page at a time. fetch returns one page and
+// the length of the whole list.
+func getAll[T any](fetch func(Page) ([]T, int, error)) ([]T, error) {
+	var all []T
+	for {
+		items, total, err := fetch(Page{StartIndex: len(all), Limit: pageSize})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) < pageSize || len(all) >= total {
+			return all, nil
+		}
+	}
+}
+
+// getMusicItemsPage fetches one page of a list of items.
+func (c *Client) getMusicItemsPage(ctx context.Context, endpoint, what string, page Page) (*MusicItemsResponse, error) {
+	var itemsResp MusicItemsResponse
+	if err := c.getPage(ctx, endpoint, what, page, &itemsResp); err != nil {
+		return nil, err
+	}
+	return &itemsResp, nil
+}
+
+// getMusicItems fetches a whole list of items.
+func (c *Client) getMusicItems(ctx context.Context, endpoint, what string) ([]MusicItem, error) {
+	return getAll(func(page Page) ([]MusicItem, int, error) {
+		itemsResp, err := c.getMusicItemsPage(ctx, endpoint, what, page)
+		if err != nil {
+			return nil, 0, err
+		}
+		return itemsResp.Items, itemsResp.TotalRecordCount, nil
+	})
+}
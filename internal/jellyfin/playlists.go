@@ -0,0 +1,120 @@
+package jellyfin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (c *Client) GetPlaylists(ctx context.Context) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.playlistsEndpoint(), "playlists")
+}
+
+func (c *Client) playlistsEndpoint() string {
+	return fmt.Sprintf("%s/Users/%s/Items?IncludeItemTypes=Playlist&MediaTypes=Audio&Recursive=true&SortBy=SortName",
+		c.ServerURL, c.UserID)
+}
+
+// GetPlaylistItems returns the tracks of a playlist in order. Each one has
+// its PlaylistItemID set.
+func (c *Client) GetPlaylistItems(ctx context.Context, playlistID string) ([]MusicItem, error) {
+	return c.getMusicItems(ctx, c.playlistItemsEndpoint(playlistID), "playlist items")
+}
+
+func (c *Client) playlistItemsEndpoint(playlistID string) string {
+	return fmt.Sprintf("%s/Playlists/%s/Items?UserId=%s&EnableUserData=true", c.ServerURL, playlistID, c.UserID)
+}
+
+// CreatePlaylist creates an audio playlist holding itemIDs, which may be
+// empty, and returns its ID.
+func (c *Client) CreatePlaylist(ctx context.Context, name string, itemIDs []string) (string, error) {
+	if c.Token == "" || c.UserID == "" {
+		return "", fmt.Errorf("not authenticated")
+	}
+
+	payload := map[string]interface{}{
+		"Name":      name,
+		"Ids":       itemIDs,
+		"UserId":    c.UserID,
+		"MediaType": "Audio",
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.ServerURL+"/Playlists", bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.addHeaders(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to create playlist: %s", resp.Status)
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// AddToPlaylist appends items to the end of a playlist.
+func (c *Client) AddToPlaylist(ctx context.Context, playlistID string, itemIDs []string) error {
+	params := url.Values{}
+	params.Set("Ids", strings.Join(itemIDs, ","))
+	params.Set("UserId", c.UserID)
+	endpoint := fmt.Sprintf("%s/Playlists/%s/Items?%s", c.ServerURL, playlistID, params.Encode())
+	return c.editPlaylist(ctx, "POST", endpoint, "add to playlist")
+}
+
+// RemoveFromPlaylist removes entries, given by their PlaylistItemID.
+func (c *Client) RemoveFromPlaylist(ctx context.Context, playlistID string, entryIDs []string) error {
+	params := url.Values{}
+	params.Set("EntryIds", strings.Join(entryIDs, ","))
+	endpoint := fmt.Sprintf("%s/Playlists/%s/Items?%s", c.ServerURL, playlistID, params.Encode())
+	return c.editPlaylist(ctx, "DELETE", endpoint, "remove from playlist")
+}
+
+// MovePlaylistItem moves an entry, given by its PlaylistItemID, to newIndex.
+func (c *Client) MovePlaylistItem(ctx context.Context, playlistID, entryID string, newIndex int) error {
+	endpoint := fmt.Sprintf("%s/Playlists/%s/Items/%s/Move/%d", c.ServerURL, playlistID, entryID, newIndex)
+	return c.editPlaylist(ctx, "POST", endpoint, "move playlist item")
+}
+
+func (c *Client) editPlaylist(ctx context.Context, method, endpoint, action string) error {
+	if c.Token == "" || c.UserID == "" {
+		return fmt.Errorf("not authenticated")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	c.addHeaders(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to %s: %s", action, resp.Status)
+	}
+	return nil
+}
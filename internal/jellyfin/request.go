@@ -0,0 +1,93 @@
+package jellyfin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultTimeout is how long a request may take, reading the response
+	// included, unless the client says otherwise.
+	DefaultTimeout = 30 * time.Second
+	// DefaultRetries is how many times a failed GET is retried unless the
+	// client says otherwise.
+	DefaultRetries = 2
+
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 8 * time.Second
+)
+
+// do sends a request, giving up after c.Timeout. GET requests, which are
+// safe to repeat, are retried with a growing delay when the connection
+// fails or the server is briefly unavailable.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retries := 0
+	if req.Method == http.MethodGet {
+		retries = c.Retries
+	}
+
+	delay := minRetryDelay
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(req)
+		if attempt >= retries || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// send makes one attempt at a request. The timeout runs until the response
+// body is closed.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.Timeout <= 0 {
+		return c.HTTPClient.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.Timeout)
+	resp, err := c.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryable reports whether a failed attempt is worth repeating: the
+// connection failed or timed out, or the server asked to come back later.
+// Nothing is retried once the caller has given up.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,125 @@
+package jellyfin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flaky answers with statuses in turn, repeating the last one, and counts
+// the requests it gets.
+type flaky struct {
+	statuses []int
+	requests atomic.Int32
+	// hang keeps the first request waiting until the client gives up.
+	hang bool
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := int(f.requests.Add(1))
+	if f.hang && n == 1 {
+		<-r.Context().Done()
+		return
+	}
+	w.WriteHeader(f.statuses[min(n, len(f.statuses))-1])
+}
+
+func testClient(t *testing.T, srv *flaky) (*Client, string) {
+	t.Helper()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return NewClient(ts.URL, "token", "user", Device{ID: "device"}), ts.URL
+}
+
+func TestDoRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		retries  int
+		statuses []int
+		want     int
+		requests int32
+	}{
+		{"ok", "GET", 2, []int{200}, 200, 1},
+		{"unavailable once", "GET", 2, []int{503, 200}, 200, 2},
+		{"too many requests", "GET", 2, []int{429, 200}, 200, 2},
+		{"bad gateway throughout", "GET", 2, []int{502}, 502, 3},
+		{"not found", "GET", 2, []int{404, 200}, 404, 1},
+		{"server error", "GET", 2, []int{500, 200}, 500, 1},
+		{"retries off", "GET", 0, []int{503, 200}, 503, 1},
+		{"post", "POST", 2, []int{503, 200}, 503, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv := &flaky{statuses: tt.statuses}
+			c, url := testClient(t, srv)
+			c.Retries = tt.retries
+
+			req, err := http.NewRequest(tt.method, url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if n := srv.requests.Load(); n != tt.requests {
+				t.Errorf("sent %d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestDoRetriesTimeout(t *testing.T) {
+	srv := &flaky{statuses: []int{200}, hang: true}
+	c, url := testClient(t, srv)
+	c.Timeout = 50 * time.Millisecond
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if n := srv.requests.Load(); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+}
+
+func TestDoStopsWhenCancelled(t *testing.T) {
+	srv := &flaky{statuses: []int{503}}
+	c, url := testClient(t, srv)
+	c.Retries = 5
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := c.do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d >= minRetryDelay {
+		t.Errorf("gave up after %v, want before the retry at %v", d, minRetryDelay)
+	}
+	if n := srv.requests.Load(); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
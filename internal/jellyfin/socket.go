@@ -0,0 +1,224 @@
+package jellyfin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultKeepAlive is how often the socket is kept alive until the
+	// server asks for another interval.
+	defaultKeepAlive   = 30 * time.Second
+	socketWriteTimeout = 10 * time.Second
+)
+
+// ErrUnauthorized means the server refused the access token of the session.
+var ErrUnauthorized = errors.New("access token was refused")
+
+// Capabilities tell the server what this client plays and which commands
+// other clients may send to its session.
+type Capabilities struct {
+	PlayableMediaTypes   []string `json:"PlayableMediaTypes"`
+	SupportedCommands    []string `json:"SupportedCommands"`
+	SupportsMediaControl bool     `json:"SupportsMediaControl"`
+}
+
+// ReportCapabilities registers the capabilities of this client's session.
+func (c *Client) ReportCapabilities(ctx context.Context, caps Capabilities) error {
+	if c.Token == "" || c.UserID == "" {
+		return fmt.Errorf("not authenticated")
+	}
+
+	body, err := json.Marshal(caps)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.ServerURL+"/Sessions/Capabilities/Full", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.addHeaders(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("failed to report capabilities: %w", ErrUnauthorized)
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to report capabilities: %s", resp.Status)
+	}
+	return nil
+}
+
+// SocketMessage is a message on the session socket. Data depends on
+// MessageType.
+type SocketMessage struct {
+	MessageType string          `json:"MessageType"`
+	Data        json.RawMessage `json:"Data,omitempty"`
+}
+
+// PlaystateRequest is the Data of a Playstate message.
+type PlaystateRequest struct {
+	// Command is Stop, Pause, Unpause, PlayPause, NextTrack, PreviousTrack,
+	// Seek, Rewind or FastForward.
+	Command           string `json:"Command"`
+	SeekPositionTicks int64  `json:"SeekPositionTicks"`
+}
+
+// PlayRequest is the Data of a Play message.
+type PlayRequest struct {
+	ItemIDs            []string `json:"ItemIds"`
+	StartPositionTicks int64    `json:"StartPositionTicks"`
+	// PlayCommand is PlayNow, PlayNext, PlayLast, PlayShuffle or
+	// PlayInstantMix.
+	PlayCommand string `json:"PlayCommand"`
+	StartIndex  int    `json:"StartIndex"`
+}
+
+// GeneralCommand is the Data of a GeneralCommand message.
+type GeneralCommand struct {
+	Name      string            `json:"Name"`
+	Arguments map[string]string `json:"Arguments"`
+}
+
+// Socket is the session WebSocket, over which the server relays commands
+// from other clients. It keeps itself alive until closed.
+type Socket struct {
+	conn *websocket.Conn
+	// timeout is how long Read waits for any message, keep-alive answers
+	// included, before giving up on the server.
+	timeout time.Duration
+
+	writeMu   sync.Mutex
+	keepAlive chan time.Duration
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenSocket connects to the session socket of the server. ctx only
+// limits connecting; the socket stays open until closed.
+func (c *Client) OpenSocket(ctx context.Context) (*Socket, error) {
+	if c.Token == "" || c.UserID == "" {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	u, err := url.Parse(c.ServerURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/socket"
+	u.RawQuery = url.Values{"api_key": {c.Token}, "deviceId": {c.Device.ID}}.Encode()
+
+	header := http.Header{}
+	header.Set("X-Emby-Authorization", c.authorization())
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: socketWriteTimeout,
+	}
+	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("failed to open socket: %w", ErrUnauthorized)
+		}
+		if resp != nil {
+			return nil, fmt.Errorf("failed to open socket: %s", resp.Status)
+		}
+		return nil, err
+	}
+
+	s := &Socket{
+		conn:      conn,
+		timeout:   3 * defaultKeepAlive,
+		keepAlive: make(chan time.Duration, 1),
+		done:      make(chan struct{}),
+	}
+	go s.keepAliveLoop()
+	return s, nil
+}
+
+// Read returns the next message from the server. Keep-alive messages are
+// handled here and never returned.
+func (s *Socket) Read() (SocketMessage, error) {
+	for {
+		var msg SocketMessage
+		s.conn.SetReadDeadline(time.Now().Add(s.timeout))
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			return msg, err
+		}
+		switch msg.MessageType {
+		case "ForceKeepAlive":
+			// The server drops the socket after Data seconds of silence.
+			var seconds int
+			if json.Unmarshal(msg.Data, &seconds) == nil && seconds > 0 {
+				interval := time.Duration(seconds) * time.Second / 2
+				s.timeout = 3 * interval
+				select {
+				case s.keepAlive <- interval:
+				default:
+				}
+			}
+		case "KeepAlive":
+		default:
+			return msg, nil
+		}
+	}
+}
+
+func (s *Socket) keepAliveLoop() {
+	interval := defaultKeepAlive
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case interval = <-s.keepAlive:
+			timer.Reset(interval)
+		case <-timer.C:
+			if err := s.send(SocketMessage{MessageType: "KeepAlive"}); err != nil {
+				s.Close()
+				return
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+func (s *Socket) send(msg SocketMessage) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	return s.conn.WriteJSON(msg)
+}
+
+// Close disconnects from the server. A pending Read returns an error.
+func (s *Socket) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.conn.Close()
+	})
+	return err
+}
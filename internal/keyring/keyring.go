@@ -0,0 +1,223 @@
+// Package keyring keeps secrets in the freedesktop Secret Service (GNOME
+// Keyring, KeePassXC, KWallet and others) over the D-Bus session bus.
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	serviceName       = "org.freedesktop.secrets"
+	servicePath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	defaultCollection = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	noPrompt          = dbus.ObjectPath("/")
+
+	serviceIface    = "org.freedesktop.Secret.Service"
+	sessionIface    = "org.freedesktop.Secret.Session"
+	collectionIface = "org.freedesktop.Secret.Collection"
+	itemIface       = "org.freedesktop.Secret.Item"
+	promptIface     = "org.freedesktop.Secret.Prompt"
+
+	// promptTimeout is how long the user has to answer an unlock prompt.
+	promptTimeout = 2 * time.Minute
+)
+
+var (
+	// ErrUnavailable means there is no session bus or no Secret Service on
+	// it.
+	ErrUnavailable = errors.New("secret service is not available")
+	// ErrNotFound means no secret is stored for the service and user.
+	ErrNotFound = errors.New("secret not found in keyring")
+
+	errDismissed = errors.New("keyring prompt was dismissed")
+)
+
+// secret is the Secret struct of the Secret Service API.
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// conn is an open session with the Secret Service. Secrets travel over the
+// session bus unencrypted, which is what the "plain" algorithm means.
+type conn struct {
+	bus     *dbus.Conn
+	service dbus.BusObject
+	session dbus.ObjectPath
+}
+
+func open() (*conn, error) {
+	bus, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	service := bus.Object(serviceName, servicePath)
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := service.Call(serviceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		bus.Close()
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return &conn{bus: bus, service: service, session: session}, nil
+}
+
+func (c *conn) close() {
+	c.bus.Object(serviceName, c.session).Call(sessionIface+".Close", 0)
+	c.bus.Close()
+}
+
+// Get returns the secret stored for user of service.
+func Get(service, user string) (string, error) {
+	c, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer c.close()
+
+	items, err := c.search(service, user)
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", ErrNotFound
+	}
+
+	var s secret
+	if err := c.bus.Object(serviceName, items[0]).Call(itemIface+".GetSecret", 0, c.session).Store(&s); err != nil {
+		return "", err
+	}
+	return string(s.Value), nil
+}
+
+// Set stores secret for user of service in the default collection,
+// replacing any earlier one.
+func Set(service, user, password string) error {
+	c, err := open()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	if err := c.unlock([]dbus.ObjectPath{defaultCollection}); err != nil {
+		return err
+	}
+
+	props := map[string]dbus.Variant{
+		itemIface + ".Label":      dbus.MakeVariant(fmt.Sprintf("%s (%s)", service, user)),
+		itemIface + ".Attributes": dbus.MakeVariant(attributes(service, user)),
+	}
+	s := secret{
+		Session:     c.session,
+		Parameters:  []byte{},
+		Value:       []byte(password),
+		ContentType: "text/plain; charset=utf8",
+	}
+	var item, prompt dbus.ObjectPath
+	collection := c.bus.Object(serviceName, defaultCollection)
+	if err := collection.Call(collectionIface+".CreateItem", 0, props, s, true).Store(&item, &prompt); err != nil {
+		return err
+	}
+	_, err = c.prompt(prompt)
+	return err
+}
+
+// Delete removes the secret stored for user of service, if any.
+func Delete(service, user string) error {
+	c, err := open()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	items, err := c.search(service, user)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		var prompt dbus.ObjectPath
+		if err := c.bus.Object(serviceName, item).Call(itemIface+".Delete", 0).Store(&prompt); err != nil {
+			return err
+		}
+		if _, err := c.prompt(prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func attributes(service, user string) map[string]string {
+	return map[string]string{"service": service, "username": user}
+}
+
+// search returns the items of user of service, unlocking them if needed.
+func (c *conn) search(service, user string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := c.service.Call(serviceIface+".SearchItems", 0, attributes(service, user)).Store(&unlocked, &locked); err != nil {
+		return nil, err
+	}
+	if len(locked) > 0 {
+		if err := c.unlock(locked); err != nil {
+			return nil, err
+		}
+		unlocked = append(unlocked, locked...)
+	}
+	return unlocked, nil
+}
+
+func (c *conn) unlock(objects []dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := c.service.Call(serviceIface+".Unlock", 0, objects).Store(&unlocked, &prompt); err != nil {
+		return err
+	}
+	_, err := c.prompt(prompt)
+	return err
+}
+
+// prompt shows a prompt of the service, such as the password dialog that
+// unlocks the keyring, and waits for the user to answer it.
+func (c *conn) prompt(path dbus.ObjectPath) (dbus.Variant, error) {
+	if path == noPrompt || path == "" {
+		return dbus.Variant{}, nil
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(promptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := c.bus.AddMatchSignal(match...); err != nil {
+		return dbus.Variant{}, err
+	}
+	defer c.bus.RemoveMatchSignal(match...)
+	signals := make(chan *dbus.Signal, 8)
+	c.bus.Signal(signals)
+	defer c.bus.RemoveSignal(signals)
+
+	if err := c.bus.Object(serviceName, path).Call(promptIface+".Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, err
+	}
+
+	timeout := time.After(promptTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Path != path || sig.Name != promptIface+".Completed" || len(sig.Body) < 2 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return dbus.Variant{}, errDismissed
+			}
+			result, _ := sig.Body[1].(dbus.Variant)
+			return result, nil
+		case <-timeout:
+			return dbus.Variant{}, errors.New("keyring prompt timed out")
+		}
+	}
+}
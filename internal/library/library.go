@@ -0,0 +1,328 @@
+// Package library keeps the artists, albums and tracks of a Jellyfin library
+// on disk, so they can be browsed at startup before the server answers. The
+// cache is brought up to date by asking the server only for what changed
+// since the last time.
+package library
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+)
+
+const (
+	tmpSuffix = ".tmp"
+
+	// syncOverlap widens each refresh back in time, so changes saved while
+	// the previous one ran, or under a clock slightly off the server's,
+	// are not missed.
+	syncOverlap = time.Minute
+	// artistPageSize is how many artists a refresh loads at least when the
+	// artist list has to be reloaded.
+	artistPageSize = 200
+)
+
+type data struct {
+	ServerURL string `json:"server_url"`
+	UserID    string `json:"user_id"`
+	// Synced is when the cache was last brought up to date. It is zero
+	// until the first artists are loaded.
+	Synced time.Time `json:"synced"`
+	// Artists holds the first artists of the library, by name, out of
+	// ArtistTotal.
+	Artists     []jellyfin.MusicItem `json:"artists"`
+	ArtistTotal int                  `json:"artist_total"`
+	// Albums maps artist IDs to their albums, and Tracks album IDs to
+	// their tracks, for those opened so far.
+	Albums map[string][]jellyfin.MusicItem `json:"albums"`
+	Tracks map[string][]jellyfin.MusicItem `json:"tracks"`
+}
+
+// Cache is the library of one user on one server as last seen. It is safe
+// for concurrent use. A nil Cache caches nothing, so callers without a
+// usable cache directory need no special case.
+type Cache struct {
+	path string
+
+	mu    sync.Mutex
+	data  data
+	dirty bool
+}
+
+// DefaultDir returns the cache directory under the user cache directory.
+func DefaultDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "jellyfin-mustui", "library"), nil
+}
+
+// Open loads the cache of userID on serverURL from dir, creating the
+// directory if needed. A missing or unreadable cache file starts an empty
+// cache.
+func Open(dir, serverURL, userID string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(serverURL + "\n" + userID))
+	c := &Cache{path: filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")}
+	if raw, err := os.ReadFile(c.path); err == nil {
+		json.Unmarshal(raw, &c.data)
+	}
+	if c.data.ServerURL != serverURL || c.data.UserID != userID {
+		c.data = data{ServerURL: serverURL, UserID: userID}
+	}
+	return c, nil
+}
+
+// OpenDefault opens the cache of userID on serverURL in DefaultDir.
+func OpenDefault(serverURL, userID string) (*Cache, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(dir, serverURL, userID)
+}
+
+// Artists returns the cached artists and the number of artists in the
+// whole library.
+func (c *Cache) Artists() ([]jellyfin.MusicItem, int) {
+	if c == nil {
+		return nil, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.data.Artists), c.data.ArtistTotal
+}
+
+// AddArtists stores a page of artists loaded from the server. Pages that do
+// not follow the cached artists are ignored.
+func (c *Cache) AddArtists(start int, items []jellyfin.MusicItem, total int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if start > len(c.data.Artists) {
+		return
+	}
+	c.data.Artists = append(c.data.Artists[:start:start], items...)
+	c.data.ArtistTotal = total
+	if c.data.Synced.IsZero() {
+		c.data.Synced = time.Now()
+	}
+	c.dirty = true
+}
+
+// Albums returns the cached albums of an artist.
+func (c *Cache) Albums(artistID string) ([]jellyfin.MusicItem, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	albums, ok := c.data.Albums[artistID]
+	return slices.Clone(albums), ok
+}
+
+// SetAlbums stores the albums of an artist.
+func (c *Cache) SetAlbums(artistID string, albums []jellyfin.MusicItem) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data.Albums == nil {
+		c.data.Albums = make(map[string][]jellyfin.MusicItem)
+	}
+	c.data.Albums[artistID] = slices.Clone(albums)
+	c.dirty = true
+}
+
+// Tracks returns the cached tracks of an album.
+func (c *Cache) Tracks(albumID string) ([]jellyfin.MusicItem, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tracks, ok := c.data.Tracks[albumID]
+	return slices.Clone(tracks), ok
+}
+
+// SetTracks stores the tracks of an album.
+func (c *Cache) SetTracks(albumID string, tracks []jellyfin.MusicItem) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data.Tracks == nil {
+		c.data.Tracks = make(map[string][]jellyfin.MusicItem)
+	}
+	c.data.Tracks[albumID] = slices.Clone(tracks)
+	c.dirty = true
+}
+
+// SetFavorite records the favorite flag of an item wherever it is cached.
+func (c *Cache) SetFavorite(id string, favorite bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.update(id, func(item *jellyfin.MusicItem) {
+		item.UserData.IsFavorite = favorite
+	})
+}
+
+// update applies f to every cached copy of an item.
+func (c *Cache) update(id string, f func(*jellyfin.MusicItem)) bool {
+	found := false
+	apply := func(items []jellyfin.MusicItem) {
+		for i := range items {
+			if items[i].ID == id {
+				f(&items[i])
+				found = true
+			}
+		}
+	}
+	apply(c.data.Artists)
+	for _, albums := range c.data.Albums {
+		apply(albums)
+	}
+	for _, tracks := range c.data.Tracks {
+		apply(tracks)
+	}
+	if found {
+		c.dirty = true
+	}
+	return found
+}
+
+// Clear forgets everything cached, so the library loads from the server
+// again. Removed items only disappear this way.
+func (c *Cache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = data{ServerURL: c.data.ServerURL, UserID: c.data.UserID}
+	c.dirty = true
+}
+
+// Refresh asks the server what changed since the cache was last brought up
+// to date and applies it. Changed items are updated in place; albums and
+// track lists they may belong to are dropped, to be loaded again when next
+// opened. It reports whether the cached artists changed.
+func (c *Cache) Refresh(ctx context.Context, client *jellyfin.Client) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	c.mu.Lock()
+	since := c.data.Synced
+	loaded := len(c.data.Artists)
+	c.mu.Unlock()
+	if since.IsZero() {
+		return false, nil
+	}
+
+	start := time.Now()
+	since = since.Add(-syncOverlap)
+	saved, err := client.GetChangedItems(ctx, since, false)
+	if err != nil {
+		return false, err
+	}
+	played, err := client.GetChangedItems(ctx, since, true)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	artistsChanged, newArtists := false, false
+	for _, item := range played {
+		if c.replace(item) && item.Type == "MusicArtist" {
+			artistsChanged = true
+		}
+	}
+	for _, item := range saved {
+		found := c.replace(item)
+		switch item.Type {
+		case "MusicArtist":
+			artistsChanged = true
+			newArtists = newArtists || !found
+		case "MusicAlbum":
+			for _, artist := range item.AlbumArtists {
+				delete(c.data.Albums, artist.ID)
+			}
+			delete(c.data.Tracks, item.ID)
+		case "Audio":
+			delete(c.data.Tracks, item.AlbumID)
+		}
+		c.dirty = true
+	}
+	c.mu.Unlock()
+
+	// New artists have to be placed among the others, which only the
+	// server knows how to sort: load the cached range again.
+	if newArtists {
+		page, err := client.GetArtistsPage(ctx, jellyfin.Page{Limit: max(loaded, artistPageSize)})
+		if err != nil {
+			return false, err
+		}
+		c.mu.Lock()
+		c.data.Artists = page.Items
+		c.data.ArtistTotal = page.TotalRecordCount
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	c.data.Synced = start
+	c.dirty = true
+	c.mu.Unlock()
+	return artistsChanged, c.Save()
+}
+
+// replace swaps every cached copy of item for the new one.
+func (c *Cache) replace(item jellyfin.MusicItem) bool {
+	return c.update(item.ID, func(old *jellyfin.MusicItem) {
+		*old = item
+	})
+}
+
+// Save writes the cache to disk if anything changed since it was loaded or
+// last saved. The file is replaced atomically.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	raw, err := json.Marshal(c.data)
+	if err != nil {
+		return err
+	}
+	tmp := c.path + tmpSuffix
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
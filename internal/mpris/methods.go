@@ -0,0 +1,141 @@
+package mpris
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+var errOpenURI = errors.New("opening URIs is not supported")
+
+// root implements org.mpris.MediaPlayer2. The player runs in a terminal, so
+// it can neither be raised nor quit from outside.
+type root struct{}
+
+func (root) Raise() *dbus.Error { return nil }
+func (root) Quit() *dbus.Error  { return nil }
+
+// controls implements org.mpris.MediaPlayer2.Player.
+type controls struct {
+	s *Server
+}
+
+func (c controls) Next() *dbus.Error {
+	return c.done(c.s.player.Next())
+}
+
+func (c controls) Previous() *dbus.Error {
+	return c.done(c.s.player.Previous())
+}
+
+func (c controls) Pause() *dbus.Error {
+	c.s.player.Pause()
+	return c.done(nil)
+}
+
+func (c controls) PlayPause() *dbus.Error {
+	if c.s.player.GetState() == player.StateStopped {
+		return c.Play()
+	}
+	c.s.player.TogglePause()
+	return c.done(nil)
+}
+
+func (c controls) Stop() *dbus.Error {
+	c.s.player.Stop()
+	return c.done(nil)
+}
+
+// Play resumes when paused and restarts the current queue entry when
+// stopped.
+func (c controls) Play() *dbus.Error {
+	p := c.s.player
+	switch p.GetState() {
+	case player.StatePaused:
+		p.Resume()
+	case player.StateStopped:
+		if index := p.GetQueueIndex(); index >= 0 {
+			return c.done(p.PlayFromQueue(index))
+		}
+	}
+	return c.done(nil)
+}
+
+// SeekBy implements Seek, which is renamed on export to keep clear of
+// io.Seeker. It moves the position by offset microseconds.
+func (c controls) SeekBy(offset int64) *dbus.Error {
+	return c.done(c.s.player.SeekRelative(time.Duration(offset) * time.Microsecond))
+}
+
+// SetPosition seeks to position microseconds if trackID is still the
+// current track.
+func (c controls) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
+	track := c.s.player.GetCurrentTrack()
+	if track == nil || trackPath(track.ID) != trackID {
+		return nil
+	}
+	pos := time.Duration(position) * time.Microsecond
+	if pos < 0 || pos > c.s.player.GetDuration() {
+		return nil
+	}
+	return c.done(c.s.player.Seek(pos))
+}
+
+func (c controls) OpenUri(uri string) *dbus.Error {
+	return dbus.MakeFailedError(errOpenURI)
+}
+
+// done publishes the effect of a method call right away instead of on the
+// next refresh.
+func (c controls) done(err error) *dbus.Error {
+	c.s.wake()
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (s *Server) setLoopStatus(ch *prop.Change) *dbus.Error {
+	switch ch.Value.(string) {
+	case "None":
+		s.player.SetRepeat(player.RepeatOff)
+	case "Playlist":
+		s.player.SetRepeat(player.RepeatAll)
+	case "Track":
+		s.player.SetRepeat(player.RepeatOne)
+	default:
+		return prop.ErrInvalidArg
+	}
+	return nil
+}
+
+// setRate rejects any rate other than normal speed.
+func (s *Server) setRate(ch *prop.Change) *dbus.Error {
+	if ch.Value.(float64) != 1.0 {
+		return prop.ErrInvalidArg
+	}
+	return nil
+}
+
+func (s *Server) setShuffle(ch *prop.Change) *dbus.Error {
+	s.player.SetShuffle(ch.Value.(bool))
+	return nil
+}
+
+func (s *Server) setVolume(ch *prop.Change) *dbus.Error {
+	volume := ch.Value.(float64)
+	if volume < 0 {
+		volume = 0
+	}
+	if volume > 1 {
+		volume = 1
+	}
+	s.player.SetVolume(volume)
+	if s.player.Muted() {
+		s.player.ToggleMute()
+	}
+	return nil
+}
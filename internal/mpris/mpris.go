@@ -0,0 +1,365 @@
+// Package mpris exposes the player on the D-Bus session bus through the
+// MPRIS interfaces, so media keys, desktop widgets and playerctl can control
+// it.
+package mpris
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+const (
+	busName     = "org.mpris.MediaPlayer2.jellyfin_mustui"
+	objectPath  = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	rootIface   = "org.mpris.MediaPlayer2"
+	playerIface = "org.mpris.MediaPlayer2.Player"
+
+	noTrack     = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+	trackPrefix = "/org/jellyfin_mustui/track/"
+
+	// refreshInterval is how often properties are compared against the
+	// player to pick up changes made from the TUI.
+	refreshInterval = time.Second
+	// seekTolerance is how far the position may drift from the expected one
+	// before it counts as a seek.
+	seekTolerance = 1500 * time.Millisecond
+)
+
+// Server publishes a player on D-Bus.
+type Server struct {
+	conn   *dbus.Conn
+	player *player.Player
+	props  *prop.Properties
+	artURL func(player.Track) string
+
+	notify chan struct{}
+	quit   chan struct{}
+	wg     sync.WaitGroup
+
+	// Used by the refresh loop only.
+	lastTrackID string
+	lastPos     time.Duration
+	lastRefresh time.Time
+}
+
+// Start connects to the session bus and publishes p on it.
+func Start(p *player.Player, artURL func(player.Track) string) (*Server, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(conn, p, artURL)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New publishes p on conn, which the server takes ownership of. It hooks
+// into the player's callbacks, so it must be called after any other code
+// that sets them. artURL returns the cover image URL of a track and may be
+// nil.
+func New(conn *dbus.Conn, p *player.Player, artURL func(player.Track) string) (*Server, error) {
+	s := &Server{
+		conn:   conn,
+		player: p,
+		artURL: artURL,
+		notify: make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}
+
+	if err := conn.Export(root{}, objectPath, rootIface); err != nil {
+		return nil, err
+	}
+	if err := conn.ExportWithMap(controls{s}, controlNames, objectPath, playerIface); err != nil {
+		return nil, err
+	}
+
+	props, err := prop.Export(conn, objectPath, s.propMap())
+	if err != nil {
+		return nil, err
+	}
+	s.props = props
+
+	node := &introspect.Node{
+		Name: string(objectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       rootIface,
+				Methods:    introspect.Methods(root{}),
+				Properties: props.Introspection(rootIface),
+			},
+			{
+				Name:       playerIface,
+				Methods:    controlMethods(),
+				Properties: props.Introspection(playerIface),
+				Signals: []introspect.Signal{{
+					Name: "Seeked",
+					Args: []introspect.Arg{{Name: "Position", Type: "x"}},
+				}},
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), objectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, err
+	}
+
+	if err := s.requestName(); err != nil {
+		return nil, err
+	}
+
+	s.hook()
+	s.refresh()
+	s.wg.Add(1)
+	go s.loop()
+	return s, nil
+}
+
+// controlNames maps Go method names of controls to their D-Bus names where
+// the two differ.
+var controlNames = map[string]string{"SeekBy": "Seek"}
+
+func controlMethods() []introspect.Method {
+	methods := introspect.Methods(controls{})
+	for i, m := range methods {
+		if name, ok := controlNames[m.Name]; ok {
+			methods[i].Name = name
+		}
+	}
+	return methods
+}
+
+// requestName claims the well-known name, falling back to a per-process
+// instance name when another copy is already running.
+func (s *Server) requestName() error {
+	for _, name := range []string{busName, fmt.Sprintf("%s.instance%d", busName, os.Getpid())} {
+		reply, err := s.conn.RequestName(name, dbus.NameFlagDoNotQueue)
+		if err != nil {
+			return err
+		}
+		if reply == dbus.RequestNameReplyPrimaryOwner {
+			return nil
+		}
+	}
+	return fmt.Errorf("mpris: bus name %s is taken", busName)
+}
+
+// hook chains the server onto the player callbacks. The callbacks may run
+// with the player lock held, so they only wake the refresh loop.
+func (s *Server) hook() {
+	prevState := s.player.OnStateChange
+	s.player.OnStateChange = func(state player.State) {
+		if prevState != nil {
+			prevState(state)
+		}
+		s.wake()
+	}
+	prevTrack := s.player.OnTrackChange
+	s.player.OnTrackChange = func(t *player.Track) {
+		if prevTrack != nil {
+			prevTrack(t)
+		}
+		s.wake()
+	}
+}
+
+func (s *Server) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-s.notify:
+			s.refresh()
+		case <-ticker.C:
+			s.refresh()
+		}
+	}
+}
+
+// Close removes the player from the bus and closes the connection.
+func (s *Server) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	return s.conn.Close()
+}
+
+func (s *Server) propMap() prop.Map {
+	readOnly := func(v interface{}) *prop.Prop {
+		return &prop.Prop{Value: v, Emit: prop.EmitTrue}
+	}
+	constant := func(v interface{}) *prop.Prop {
+		return &prop.Prop{Value: v, Emit: prop.EmitConst}
+	}
+
+	return prop.Map{
+		rootIface: {
+			"CanQuit":             constant(false),
+			"CanRaise":            constant(false),
+			"HasTrackList":        constant(false),
+			"Identity":            constant("jellyfin-mustui"),
+			"SupportedUriSchemes": constant([]string{}),
+			"SupportedMimeTypes":  constant([]string{}),
+		},
+		playerIface: {
+			"PlaybackStatus": readOnly("Stopped"),
+			"LoopStatus": {
+				Value:    "None",
+				Writable: true,
+				Emit:     prop.EmitTrue,
+				Callback: s.setLoopStatus,
+			},
+			"Rate": {Value: 1.0, Writable: true, Emit: prop.EmitTrue, Callback: s.setRate},
+			"Shuffle": {
+				Value:    false,
+				Writable: true,
+				Emit:     prop.EmitTrue,
+				Callback: s.setShuffle,
+			},
+			"Metadata": readOnly(metadata(nil, "")),
+			"Volume": {
+				Value:    1.0,
+				Writable: true,
+				Emit:     prop.EmitTrue,
+				Callback: s.setVolume,
+			},
+			"Position":      {Value: int64(0), Emit: prop.EmitFalse},
+			"MinimumRate":   constant(1.0),
+			"MaximumRate":   constant(1.0),
+			"CanGoNext":     readOnly(false),
+			"CanGoPrevious": readOnly(false),
+			"CanPlay":       readOnly(false),
+			"CanPause":      readOnly(false),
+			"CanSeek":       readOnly(false),
+			"CanControl":    constant(true),
+		},
+	}
+}
+
+// refresh brings the published properties in line with the player and
+// emits Seeked when the position jumped.
+func (s *Server) refresh() {
+	track := s.player.GetCurrentTrack()
+	state := s.player.GetState()
+	pos := s.player.GetPosition()
+	tracks, index := s.player.Queue()
+	repeat := s.player.Repeat()
+
+	volume := s.player.Volume()
+	if s.player.Muted() {
+		volume = 0
+	}
+
+	var art string
+	if track != nil && s.artURL != nil {
+		art = s.artURL(*track)
+	}
+
+	s.set(playerIface, "PlaybackStatus", playbackStatus(state))
+	s.set(playerIface, "LoopStatus", loopStatus(repeat))
+	s.set(playerIface, "Shuffle", s.player.Shuffle())
+	s.set(playerIface, "Volume", volume)
+	s.set(playerIface, "Metadata", metadata(track, art))
+	s.set(playerIface, "CanGoNext", index+1 < len(tracks) || (repeat != player.RepeatOff && len(tracks) > 0))
+	s.set(playerIface, "CanGoPrevious", track != nil)
+	s.set(playerIface, "CanPlay", track != nil)
+	s.set(playerIface, "CanPause", track != nil)
+	s.set(playerIface, "CanSeek", track != nil)
+	s.set(playerIface, "Position", pos.Microseconds())
+
+	trackID := ""
+	if track != nil {
+		trackID = track.ID
+	}
+	now := time.Now()
+	expected := s.lastPos
+	if state == player.StatePlaying {
+		expected += now.Sub(s.lastRefresh)
+	}
+	if trackID != "" && trackID == s.lastTrackID && (pos-expected > seekTolerance || expected-pos > seekTolerance) {
+		s.conn.Emit(objectPath, playerIface+".Seeked", pos.Microseconds())
+	}
+	s.lastTrackID = trackID
+	s.lastPos = pos
+	s.lastRefresh = now
+}
+
+// set updates a property, emitting a change signal only when the value
+// differs from the published one.
+func (s *Server) set(iface, name string, value interface{}) {
+	if reflect.DeepEqual(s.props.GetMust(iface, name), value) {
+		return
+	}
+	s.props.SetMust(iface, name, value)
+}
+
+func playbackStatus(state player.State) string {
+	switch state {
+	case player.StatePlaying:
+		return "Playing"
+	case player.StatePaused:
+		return "Paused"
+	default:
+		return "Stopped"
+	}
+}
+
+func loopStatus(mode player.RepeatMode) string {
+	switch mode {
+	case player.RepeatAll:
+		return "Playlist"
+	case player.RepeatOne:
+		return "Track"
+	default:
+		return "None"
+	}
+}
+
+func metadata(track *player.Track, art string) map[string]dbus.Variant {
+	if track == nil {
+		return map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(noTrack)}
+	}
+	md := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackPath(track.ID)),
+		"mpris:length":  dbus.MakeVariant(track.Duration.Microseconds()),
+		"xesam:title":   dbus.MakeVariant(track.Name),
+		"xesam:artist":  dbus.MakeVariant([]string{track.Artist}),
+		"xesam:album":   dbus.MakeVariant(track.Album),
+	}
+	if art != "" {
+		md["mpris:artUrl"] = dbus.MakeVariant(art)
+	}
+	return md
+}
+
+// trackPath turns a Jellyfin item ID into a valid D-Bus object path.
+func trackPath(id string) dbus.ObjectPath {
+	clean := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, id)
+	return dbus.ObjectPath(trackPrefix + clean)
+}
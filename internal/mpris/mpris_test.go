@@ -0,0 +1,172 @@
+package mpris
+
+import (
+	"bufio"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	"github.com/godbus/dbus/v5"
+)
+
+// privateBus starts a session bus of its own for the test and returns its
+// address. The test is skipped when dbus-daemon is not installed.
+func privateBus(t *testing.T) string {
+	t.Helper()
+	path, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	cmd := exec.Command(path, "--session", "--nofork", "--print-address=1")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	line, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading bus address: %v", err)
+	}
+	return strings.TrimSpace(line)
+}
+
+// writeWAV writes d of silence as a 16-bit mono WAV file.
+func writeWAV(t *testing.T, d time.Duration) string {
+	t.Helper()
+	const rate = 8000
+	samples := int(d.Seconds() * rate)
+	data := make([]byte, 44+2*samples)
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1)
+	binary.LittleEndian.PutUint16(data[22:], 1)
+	binary.LittleEndian.PutUint32(data[24:], rate)
+	binary.LittleEndian.PutUint32(data[28:], 2*rate)
+	binary.LittleEndian.PutUint16(data[32:], 2)
+	binary.LittleEndian.PutUint16(data[34:], 16)
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(2*samples))
+
+	path := filepath.Join(t.TempDir(), "track.wav")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// waitFor polls a property of the player interface until it holds want.
+func waitFor(t *testing.T, obj dbus.BusObject, name string, want func(dbus.Variant) bool) dbus.Variant {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		v, err := obj.GetProperty(playerIface + "." + name)
+		if err != nil {
+			t.Fatalf("getting %s: %v", name, err)
+		}
+		if want(v) {
+			return v
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is %v", name, v)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func isString(s string) func(dbus.Variant) bool {
+	return func(v dbus.Variant) bool {
+		got, _ := v.Value().(string)
+		return got == s
+	}
+}
+
+func TestServer(t *testing.T) {
+	addr := privateBus(t)
+
+	track := player.Track{ID: "abc-123", Name: "Song", Artist: "Band", Album: "Record", Duration: 10 * time.Second}
+	wav := writeWAV(t, track.Duration)
+	p := player.New()
+	p.LocalFile = func(id string) string { return wav }
+
+	serverConn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(serverConn, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	obj := conn.Object(busName, objectPath)
+
+	waitFor(t, obj, "PlaybackStatus", isString("Stopped"))
+
+	if err := p.PlayTracks([]player.Track{track}, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	waitFor(t, obj, "PlaybackStatus", isString("Playing"))
+
+	md := waitFor(t, obj, "Metadata", func(v dbus.Variant) bool {
+		md, _ := v.Value().(map[string]dbus.Variant)
+		return md["mpris:trackid"].Value() == trackPath(track.ID)
+	}).Value().(map[string]dbus.Variant)
+	if got := md["xesam:title"].Value(); got != track.Name {
+		t.Errorf("xesam:title = %v, want %q", got, track.Name)
+	}
+	if got := md["mpris:length"].Value(); got != track.Duration.Microseconds() {
+		t.Errorf("mpris:length = %v, want %d", got, track.Duration.Microseconds())
+	}
+
+	if err := obj.Call(playerIface+".PlayPause", 0).Err; err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, obj, "PlaybackStatus", isString("Paused"))
+
+	if err := conn.AddMatchSignal(dbus.WithMatchInterface(playerIface), dbus.WithMatchMember("Seeked")); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+
+	pos := 5 * time.Second
+	if err := obj.Call(playerIface+".SetPosition", 0, trackPath(track.ID), pos.Microseconds()).Err; err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(3 * time.Second)
+	for seeked := false; !seeked; {
+		select {
+		case sig := <-signals:
+			if sig.Name == playerIface+".Seeked" && len(sig.Body) == 1 && sig.Body[0] == pos.Microseconds() {
+				seeked = true
+			}
+		case <-timeout:
+			t.Fatal("no Seeked signal after SetPosition")
+		}
+	}
+
+	if err := obj.Call(playerIface+".PlayPause", 0).Err; err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, obj, "PlaybackStatus", isString("Playing"))
+}
@@ -0,0 +1,372 @@
+// Package offline keeps downloaded tracks on disk so they play without a
+// connection to the server. Albums, artists and playlists are marked for
+// offline use as collections; an index per server and user next to the
+// files records their metadata so they can be browsed offline too.
+package offline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+)
+
+const (
+	// indexDir holds the index of each server and user.
+	indexDir = "index"
+	// legacyIndexName is the single index kept before there was one per
+	// user. The first user to open the cache takes it over.
+	legacyIndexName = "index.json"
+	tmpSuffix       = ".part"
+
+	// DefaultLimit is the cache size used when the config sets none.
+	DefaultLimit = 4 << 30
+)
+
+// ErrFull is returned when a collection does not fit in the cache even
+// after evicting every track that is not part of it.
+var ErrFull = errors.New("offline cache is full")
+
+// Collection is an album, artist or playlist marked for offline use.
+type Collection struct {
+	Item   jellyfin.MusicItem   `json:"item"`
+	Tracks []jellyfin.MusicItem `json:"tracks"`
+}
+
+type index struct {
+	Collections []Collection `json:"collections"`
+}
+
+// Cache is a size-capped directory of downloaded tracks. Files are named
+// after the item ID, so other processes and users sharing the directory see
+// new downloads at once. When the cache grows past its limit the least
+// recently played files are removed first.
+type Cache struct {
+	dir   string
+	limit int64
+	// indexPath is the index of the user the cache was opened for.
+	indexPath string
+
+	mu    sync.Mutex
+	index index
+}
+
+// DefaultDir returns the cache directory under the user cache directory.
+func DefaultDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "jellyfin-mustui", "offline"), nil
+}
+
+// Open loads the cache in dir with the collections of userID on serverURL,
+// creating the directory if needed. A limit of zero or less means
+// DefaultLimit.
+func Open(dir, serverURL, userID string, limit int64) (*Cache, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if err := os.MkdirAll(filepath.Join(dir, indexDir), 0755); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(serverURL + "\n" + userID))
+	c := &Cache{
+		dir:       dir,
+		limit:     limit,
+		indexPath: filepath.Join(dir, indexDir, hex.EncodeToString(sum[:8])+".json"),
+	}
+	if _, err := os.Stat(c.indexPath); os.IsNotExist(err) {
+		os.Rename(filepath.Join(dir, legacyIndexName), c.indexPath)
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ForUser opens the same cache with the collections of userID on
+// serverURL.
+func (c *Cache) ForUser(serverURL, userID string) (*Cache, error) {
+	return Open(c.dir, serverURL, userID, c.limit)
+}
+
+// load reads the index from disk, where another process may have changed
+// it. The caller holds c.mu or has the cache to itself.
+func (c *Cache) load() error {
+	idx, err := readIndex(c.indexPath)
+	if err != nil {
+		return err
+	}
+	c.index = idx
+	return nil
+}
+
+func readIndex(path string) (index, error) {
+	var idx index
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return idx, err
+	}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return idx, fmt.Errorf("offline index: %w", err)
+	}
+	return idx, nil
+}
+
+func (c *Cache) file(id string) string {
+	return filepath.Join(c.dir, id)
+}
+
+// validID rejects IDs that would escape the cache directory.
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
+
+// Path returns the downloaded file of a track, or "" when it is not cached.
+// Looking a track up counts as using it for eviction purposes.
+func (c *Cache) Path(id string) string {
+	if !validID(id) {
+		return ""
+	}
+	path := c.file(id)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return path
+}
+
+// Has reports whether the item is marked for offline use.
+func (c *Cache) Has(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.find(id) >= 0
+}
+
+func (c *Cache) find(id string) int {
+	for i, col := range c.index.Collections {
+		if col.Item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Collections returns the items marked for offline use, in the order they
+// were added.
+func (c *Cache) Collections() []jellyfin.MusicItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items := make([]jellyfin.MusicItem, len(c.index.Collections))
+	for i, col := range c.index.Collections {
+		items[i] = col.Item
+	}
+	return items
+}
+
+// Tracks returns the downloaded tracks of a collection. Tracks that were
+// evicted since are left out.
+func (c *Cache) Tracks(id string) []jellyfin.MusicItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.find(id)
+	if i < 0 {
+		return nil
+	}
+	var tracks []jellyfin.MusicItem
+	for _, t := range c.index.Collections[i].Tracks {
+		if _, err := os.Stat(c.file(t.ID)); err == nil {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// Add downloads the tracks of item that are not cached yet, then marks item
+// for offline use. Tracks downloaded before a failure stay in the cache.
+func (c *Cache) Add(ctx context.Context, client *jellyfin.Client, item jellyfin.MusicItem, tracks []jellyfin.MusicItem) error {
+	keep := make(map[string]bool, len(tracks))
+	for _, t := range tracks {
+		keep[t.ID] = true
+	}
+	for _, t := range tracks {
+		if !validID(t.ID) {
+			return fmt.Errorf("invalid item ID %q", t.ID)
+		}
+		if _, err := os.Stat(c.file(t.ID)); err == nil {
+			continue
+		}
+		if err := c.download(ctx, client, t.ID); err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		if err := c.evict(keep); err != nil {
+			os.Remove(c.file(t.ID))
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return err
+	}
+	col := Collection{Item: item, Tracks: tracks}
+	if i := c.find(item.ID); i >= 0 {
+		c.index.Collections[i] = col
+	} else {
+		c.index.Collections = append(c.index.Collections, col)
+	}
+	return c.save()
+}
+
+// Remove unmarks a collection and deletes its tracks unless another
+// collection, of this user or another one, still uses them.
+func (c *Cache) Remove(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return err
+	}
+	i := c.find(id)
+	if i < 0 {
+		return nil
+	}
+	removed := c.index.Collections[i]
+	c.index.Collections = append(c.index.Collections[:i], c.index.Collections[i+1:]...)
+
+	cols := c.index.Collections
+	others, _ := filepath.Glob(filepath.Join(c.dir, indexDir, "*.json"))
+	for _, path := range others {
+		if path == c.indexPath {
+			continue
+		}
+		idx, err := readIndex(path)
+		if err != nil {
+			// Keep every file rather than delete one still in use.
+			return c.save()
+		}
+		cols = append(cols, idx.Collections...)
+	}
+	used := make(map[string]bool)
+	for _, col := range cols {
+		for _, t := range col.Tracks {
+			used[t.ID] = true
+		}
+	}
+	for _, t := range removed.Tracks {
+		if !used[t.ID] && validID(t.ID) {
+			os.Remove(c.file(t.ID))
+		}
+	}
+	return c.save()
+}
+
+// download fetches a track into a temporary file and moves it into place
+// once complete, so a partial file is never played.
+func (c *Cache) download(ctx context.Context, client *jellyfin.Client, id string) error {
+	body, err := client.Download(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	tmp := c.file(id) + tmpSuffix
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, c.file(id))
+}
+
+// evict removes the least recently used tracks, other than those in keep,
+// until the cache fits its limit.
+func (c *Cache) evict(keep map[string]bool) error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type cached struct {
+		id   string
+		size int64
+		used time.Time
+	}
+	var files []cached
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == legacyIndexName || strings.HasSuffix(e.Name(), tmpSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+		if !keep[e.Name()] {
+			files = append(files, cached{id: e.Name(), size: info.Size(), used: info.ModTime()})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+	for _, f := range files {
+		if total <= c.limit {
+			break
+		}
+		if err := os.Remove(c.file(f.id)); err == nil {
+			total -= f.size
+		}
+	}
+	if total > c.limit {
+		return ErrFull
+	}
+	return nil
+}
+
+// save writes the index atomically. The caller holds c.mu.
+func (c *Cache) save() error {
+	data, err := json.MarshalIndent(c.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.indexPath + tmpSuffix
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.indexPath)
+}
+
+// OpenDefault opens the cache in DefaultDir with the collections of userID
+// on serverURL.
+func OpenDefault(serverURL, userID string, limit int64) (*Cache, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(dir, serverURL, userID, limit)
+}
@@ -0,0 +1,459 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// bufferKeep is how many track buffers are kept once no longer read, so
+	// replaying a recent track needs no network.
+	bufferKeep = 8
+	// bufferChunk is the size of each read from the network.
+	bufferChunk = 64 << 10
+	// nearWindow is how far past the downloaded part a reader may seek and
+	// still wait for the download instead of fetching the range itself.
+	nearWindow = 1 << 20
+	// stallTimeout is how long a download may go without receiving data
+	// before the connection is treated as dropped.
+	stallTimeout = 15 * time.Second
+	// maxRetries is how many times in a row a dropped download is resumed
+	// before giving up. The wait grows by retryDelay with every attempt.
+	maxRetries = 5
+	retryDelay = time.Second
+)
+
+var errBufferClosed = errors.New("stream buffer closed")
+
+// buffer downloads one track to an unlinked temporary file in the
+// background. Readers are served from the file as it fills, so a dropped
+// connection only delays the download, which resumes with a range request
+// from where it stopped.
+type buffer struct {
+	// key is the stream URL without the play session.
+	key string
+	// session is the play session the stream was requested with.
+	session     string
+	client      *http.Client
+	url         string
+	contentType string
+	// size is -1 when the server did not send a length.
+	size   int64
+	ranges bool
+	file   *os.File
+	quit   chan struct{}
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	body    io.ReadCloser
+	written int64
+	done    bool
+	err     error
+	closed  bool
+	readers int
+}
+
+// startBuffer requests the stream at url for a play session and starts
+// downloading the response.
+func startBuffer(client *http.Client, url, session string) (*buffer, error) {
+	resp, err := client.Get(url + "&PlaySessionId=" + session)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to open stream: %s", resp.Status)
+	}
+
+	f, err := os.CreateTemp(bufferDir(), "jellyfin-mustui-*.buf")
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	// The open file keeps the data reachable; unlinking it right away means
+	// nothing is left behind, even after a crash.
+	os.Remove(f.Name())
+
+	b := &buffer{
+		key:         url,
+		session:     session,
+		client:      client,
+		url:         resp.Request.URL.String(),
+		contentType: resp.Header.Get("Content-Type"),
+		size:        resp.ContentLength,
+		ranges:      acceptsRanges(resp),
+		file:        f,
+		quit:        make(chan struct{}),
+		body:        resp.Body,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	go b.fill()
+	return b, nil
+}
+
+// bufferDir returns the directory for buffer files: the user cache
+// directory, as the temporary directory is often kept in memory. It returns
+// "" for the temporary directory when there is no cache directory.
+func bufferDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	dir = filepath.Join(dir, "jellyfin-mustui", "buffers")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return ""
+	}
+	return dir
+}
+
+func acceptsRanges(resp *http.Response) bool {
+	return resp.Header.Get("Accept-Ranges") == "bytes" && resp.ContentLength > 0
+}
+
+// openRange requests url starting at the given byte offset.
+func openRange(client *http.Client, url string, offset int64) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("range request failed: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// fill copies the response into the file until it is complete, resuming
+// after connection drops when the server accepts ranges.
+func (b *buffer) fill() {
+	chunk := make([]byte, bufferChunk)
+	b.mu.Lock()
+	body := b.body
+	b.mu.Unlock()
+
+	for {
+		// A connection that stops sending without failing would block
+		// forever, so close it when it stalls.
+		stall := time.AfterFunc(stallTimeout, func() { body.Close() })
+		n, err := body.Read(chunk)
+		stall.Stop()
+
+		if n > 0 {
+			if _, werr := b.file.WriteAt(chunk[:n], b.written); werr != nil {
+				b.finish(werr)
+				return
+			}
+			b.mu.Lock()
+			b.written += int64(n)
+			b.cond.Broadcast()
+			b.mu.Unlock()
+		}
+
+		complete := b.size < 0 || b.written >= b.size
+		if err == io.EOF && complete {
+			b.finish(nil)
+			return
+		}
+		if err == nil {
+			continue
+		}
+
+		body.Close()
+		if !b.ranges {
+			b.finish(err)
+			return
+		}
+		if body, err = b.resume(); err != nil {
+			b.finish(err)
+			return
+		}
+	}
+}
+
+// resume reopens the download where it stopped, waiting a little longer
+// before each attempt.
+func (b *buffer) resume() (io.ReadCloser, error) {
+	var err error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		select {
+		case <-b.quit:
+			return nil, errBufferClosed
+		case <-time.After(time.Duration(attempt) * retryDelay):
+		}
+
+		var body io.ReadCloser
+		if body, err = openRange(b.client, b.url, b.written); err == nil {
+			b.mu.Lock()
+			closed := b.closed
+			b.body = body
+			b.mu.Unlock()
+			if closed {
+				body.Close()
+				return nil, errBufferClosed
+			}
+			return body, nil
+		}
+	}
+	return nil, err
+}
+
+func (b *buffer) finish(err error) {
+	b.mu.Lock()
+	b.done = err == nil
+	b.err = err
+	b.body = nil
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// usable reports whether the buffer can still deliver the whole track.
+func (b *buffer) usable() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.closed && b.err == nil
+}
+
+func (b *buffer) idle() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.readers == 0
+}
+
+// level returns the downloaded fraction, or -1 when the size is unknown.
+func (b *buffer) level() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.done:
+		return 1
+	case b.size <= 0:
+		return -1
+	}
+	return float64(b.written) / float64(b.size)
+}
+
+func (b *buffer) close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.quit)
+	body := b.body
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	if body != nil {
+		body.Close()
+	}
+	b.file.Close()
+}
+
+func (b *buffer) newReader() *bufferReader {
+	b.mu.Lock()
+	b.readers++
+	b.mu.Unlock()
+	return &bufferReader{b: b}
+}
+
+// bufferReader reads a buffer from its own offset. Reads past the
+// downloaded part wait for the download, unless they are far ahead of it
+// and the server accepts ranges; then the reader fetches that part itself.
+type bufferReader struct {
+	b      *buffer
+	offset int64
+	closed bool
+	// remote is the reader's own range request, for reads far ahead.
+	remote io.ReadCloser
+}
+
+func (r *bufferReader) Read(p []byte) (int, error) {
+	b := r.b
+	b.mu.Lock()
+	for {
+		if r.closed || b.closed {
+			b.mu.Unlock()
+			return 0, errBufferClosed
+		}
+		if r.offset < b.written {
+			break
+		}
+		if b.done || b.size >= 0 && r.offset >= b.size {
+			b.mu.Unlock()
+			return 0, io.EOF
+		}
+		if b.ranges && (b.err != nil || r.offset-b.written > nearWindow) {
+			b.mu.Unlock()
+			return r.readRemote(p)
+		}
+		if b.err != nil {
+			err := b.err
+			b.mu.Unlock()
+			return 0, err
+		}
+		b.cond.Wait()
+	}
+	if avail := b.written - r.offset; int64(len(p)) > avail {
+		p = p[:avail]
+	}
+	b.mu.Unlock()
+
+	r.closeRemote()
+	n, err := b.file.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// readRemote reads from the reader's own range request. A failed request
+// is dropped, so the next read opens a new one.
+func (r *bufferReader) readRemote(p []byte) (int, error) {
+	if r.remote == nil {
+		body, err := openRange(r.b.client, r.b.url, r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.remote = body
+	}
+	n, err := r.remote.Read(p)
+	r.offset += int64(n)
+	if err != nil && err != io.EOF {
+		r.closeRemote()
+		if n > 0 {
+			err = nil
+		}
+	}
+	return n, err
+}
+
+func (r *bufferReader) closeRemote() {
+	if r.remote != nil {
+		r.remote.Close()
+		r.remote = nil
+	}
+}
+
+func (r *bufferReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		if r.b.size < 0 {
+			return 0, errors.New("bufferReader.Seek: unknown size")
+		}
+		abs = r.b.size + offset
+	default:
+		return 0, errors.New("bufferReader.Seek: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("bufferReader.Seek: negative position")
+	}
+	if abs != r.offset {
+		r.closeRemote()
+	}
+	r.offset = abs
+	return abs, nil
+}
+
+func (r *bufferReader) Close() error {
+	r.b.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		r.b.readers--
+	}
+	r.b.mu.Unlock()
+	r.closeRemote()
+	return nil
+}
+
+// bufferStore holds the buffers of the tracks played most recently.
+type bufferStore struct {
+	mu sync.Mutex
+	// buffers is ordered from least to most recently opened.
+	buffers []*buffer
+}
+
+// open returns a reader of the buffer of the stream at url, starting a new
+// download for session unless an earlier one is complete or still running.
+// A reused buffer keeps the session it was downloaded with, which the
+// caller should report instead: the server never sees the new one. A failed
+// buffer is left in place for its readers until it is evicted. The reader
+// is counted before the store is unlocked, so the buffer cannot be evicted
+// under it.
+func (s *bufferStore) open(client *http.Client, url, session string) (*bufferReader, error) {
+	s.mu.Lock()
+	for i := len(s.buffers) - 1; i >= 0; i-- {
+		b := s.buffers[i]
+		if b.key != url {
+			continue
+		}
+		if b.usable() {
+			s.buffers = append(append(s.buffers[:i], s.buffers[i+1:]...), b)
+			r := b.newReader()
+			s.mu.Unlock()
+			return r, nil
+		}
+		break
+	}
+	s.mu.Unlock()
+
+	b, err := startBuffer(client, url, session)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	r := b.newReader()
+	s.buffers = append(s.buffers, b)
+	s.evict()
+	s.mu.Unlock()
+	return r, nil
+}
+
+// evict closes the oldest buffers nobody reads until at most bufferKeep
+// idle ones remain. The caller holds s.mu.
+func (s *bufferStore) evict() {
+	idle := 0
+	for _, b := range s.buffers {
+		if b.idle() {
+			idle++
+		}
+	}
+	kept := s.buffers[:0]
+	for _, b := range s.buffers {
+		if idle > bufferKeep && b.idle() {
+			b.close()
+			idle--
+			continue
+		}
+		kept = append(kept, b)
+	}
+	s.buffers = kept
+}
+
+func (s *bufferStore) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.buffers {
+		b.close()
+	}
+	s.buffers = nil
+}
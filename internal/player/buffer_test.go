@@ -0,0 +1,210 @@
+package player
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// streamServer serves data like Jellyfin serves an original file. With
+// ranges off it leaves out Accept-Ranges, like a transcode. The first
+// dropAfter bytes of the first request are sent before the connection is
+// cut; zero means no drop.
+type streamServer struct {
+	data      []byte
+	ranges    bool
+	dropAfter int
+
+	mu       sync.Mutex
+	requests []string
+	sessions []string
+}
+
+func (s *streamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	first := len(s.requests) == 0
+	s.requests = append(s.requests, r.Header.Get("Range"))
+	s.sessions = append(s.sessions, r.URL.Query().Get("PlaySessionId"))
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "audio/flac")
+	if !s.ranges {
+		if r.Header.Get("Range") != "" {
+			http.Error(w, "no ranges", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		s.write(w, first)
+		return
+	}
+	if first && s.dropAfter > 0 {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		s.write(w, true)
+		return
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.data))
+}
+
+func (s *streamServer) write(w http.ResponseWriter, first bool) {
+	if !first || s.dropAfter == 0 {
+		w.Write(s.data)
+		return
+	}
+	w.Write(s.data[:s.dropAfter])
+	w.(http.Flusher).Flush()
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+func (s *streamServer) seen() (requests, sessions []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...), append([]string(nil), s.sessions...)
+}
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+// serve starts srv and keeps buffer files in a directory of the test.
+func serve(t *testing.T, srv *streamServer) string {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts.URL + "/Audio/t1/universal?api_key=token"
+}
+
+func TestBufferResume(t *testing.T) {
+	tests := []struct {
+		name      string
+		ranges    bool
+		dropAfter int
+		want      []string
+		wantErr   bool
+	}{
+		{"complete", true, 0, []string{""}, false},
+		{"resumed", true, 200 << 10, []string{"", "bytes=204800-"}, false},
+		{"dropped without ranges", false, 200 << 10, []string{""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &streamServer{data: testData(600 << 10), ranges: tt.ranges, dropAfter: tt.dropAfter}
+			url := serve(t, srv)
+
+			var store bufferStore
+			defer store.closeAll()
+			r, err := store.open(http.DefaultClient, url, "s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("read the whole track from a dropped stream")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, srv.data) {
+				t.Fatalf("read %d bytes that differ from the %d sent", len(got), len(srv.data))
+			}
+			if level := r.b.level(); level != 1 {
+				t.Errorf("level = %v, want 1", level)
+			}
+			if requests, _ := srv.seen(); !slices.Equal(requests, tt.want) {
+				t.Errorf("Range headers = %q, want %q", requests, tt.want)
+			}
+		})
+	}
+}
+
+func TestBufferReaderSeek(t *testing.T) {
+	srv := &streamServer{data: testData(300 << 10), ranges: true}
+	url := serve(t, srv)
+
+	var store bufferStore
+	defer store.closeAll()
+	r, err := store.open(http.DefaultClient, url, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, offset := range []int64{250 << 10, 10, 0, 300<<10 - 5} {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, 5)
+		if _, err := io.ReadFull(r, got); err != nil {
+			t.Fatalf("reading at %d: %v", offset, err)
+		}
+		if want := srv.data[offset : offset+5]; !bytes.Equal(got, want) {
+			t.Errorf("read %x at %d, want %x", got, offset, want)
+		}
+	}
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read past the end = %v, want EOF", err)
+	}
+}
+
+func TestBufferStoreReuse(t *testing.T) {
+	srv := &streamServer{data: testData(64 << 10), ranges: true}
+	url := serve(t, srv)
+
+	var store bufferStore
+	defer store.closeAll()
+	open := func(url, session string) *bufferReader {
+		t.Helper()
+		r, err := store.open(http.DefaultClient, url, session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(r)
+		r.Close()
+		return r
+	}
+
+	first := open(url, "s1")
+	again := open(url, "s2")
+	if again.b != first.b {
+		t.Error("replaying the track downloaded it again")
+	}
+	if again.b.session != "s1" {
+		t.Errorf("reused buffer has session %q, want s1", again.b.session)
+	}
+	other := open(url+"&UserId=other", "s3")
+	if other.b == first.b {
+		t.Error("a different stream URL reused the buffer")
+	}
+	if _, sessions := srv.seen(); !slices.Equal(sessions, []string{"s1", "s3"}) {
+		t.Errorf("sessions requested = %q, want s1 and s3", sessions)
+	}
+
+	for i := 0; i < bufferKeep; i++ {
+		open(url+"&n="+strconv.Itoa(i), "s")
+	}
+	// Eviction runs as a buffer is opened, while it is still being read.
+	if len(store.buffers) != bufferKeep+1 {
+		t.Errorf("kept %d buffers, want %d", len(store.buffers), bufferKeep+1)
+	}
+	if first.b.usable() {
+		t.Error("the least recently used buffer was not evicted")
+	}
+}
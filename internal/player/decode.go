@@ -0,0 +1,119 @@
+package player
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/flac"
+	"github.com/gopxl/beep/mp3"
+	"github.com/gopxl/beep/vorbis"
+	"github.com/gopxl/beep/wav"
+)
+
+// SupportedContainers lists the formats the player can decode natively, in the
+// "container|codec" form understood by Jellyfin's universal audio endpoint.
+// Anything else is transcoded to MP3 by the server.
+var SupportedContainers = []string{"flac", "ogg|vorbis", "oga|vorbis", "wav", "mp3"}
+
+const (
+	outputSampleRate = beep.SampleRate(44100)
+	resampleQuality  = 4
+)
+
+type decodeFunc func(io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error)
+
+// codec describes how to decode a stream and how it can be seeked.
+type codec struct {
+	decode decodeFunc
+	// seekable decoders seek directly when given an io.ReadSeeker, without
+	// first scanning the whole stream.
+	seekable bool
+	// resyncs is true when decoding can start at an arbitrary byte offset.
+	resyncs bool
+}
+
+var (
+	codecFLAC   = codec{decode: decodeFLAC, seekable: true}
+	codecVorbis = codec{decode: vorbis.Decode, seekable: true}
+	codecWAV    = codec{decode: decodeWAV, seekable: true}
+	codecMP3    = codec{decode: mp3.Decode, resyncs: true}
+)
+
+func decodeFLAC(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+	return flac.Decode(rc)
+}
+
+func decodeWAV(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+	return wav.Decode(rc)
+}
+
+var contentTypeCodecs = map[string]codec{
+	"audio/flac":   codecFLAC,
+	"audio/x-flac": codecFLAC,
+	"audio/ogg":    codecVorbis,
+	"audio/vorbis": codecVorbis,
+	"audio/wav":    codecWAV,
+	"audio/x-wav":  codecWAV,
+	"audio/wave":   codecWAV,
+	"audio/mpeg":   codecMP3,
+	"audio/mp3":    codecMP3,
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// detectCodec picks a codec from the response Content-Type, falling back to
+// sniffing the first bytes of the stream when the server sends a generic type.
+// The returned reader must be used in place of body.
+func detectCodec(body io.ReadCloser, contentType string) (codec, io.ReadCloser, error) {
+	if c, ok := codecForType(contentType); ok {
+		return c, body, nil
+	}
+
+	br := bufio.NewReader(body)
+	header, _ := br.Peek(4)
+	c, ok := sniffCodec(header)
+	if !ok {
+		body.Close()
+		return codec{}, nil, fmt.Errorf("unsupported audio stream (content type %q)", contentType)
+	}
+	return c, readCloser{Reader: br, Closer: body}, nil
+}
+
+func codecForType(contentType string) (codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return codec{}, false
+	}
+	c, ok := contentTypeCodecs[mediaType]
+	return c, ok
+}
+
+func sniffCodec(header []byte) (codec, bool) {
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return codecFLAC, true
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return codecVorbis, true
+	case bytes.HasPrefix(header, []byte("RIFF")):
+		return codecWAV, true
+	case bytes.HasPrefix(header, []byte("ID3")),
+		len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0:
+		return codecMP3, true
+	}
+	return codec{}, false
+}
+
+// resampled converts a decoded stream to the speaker's sample rate.
+func resampled(s beep.Streamer, format beep.Format) beep.Streamer {
+	if format.SampleRate == outputSampleRate {
+		return s
+	}
+	return beep.Resample(resampleQuality, format.SampleRate, outputSampleRate, s)
+}
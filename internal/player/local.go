@@ -0,0 +1,44 @@
+package player
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// openFile opens a downloaded copy of a track, positioned at start. Local
+// files are always seekable, whatever the codec.
+func openFile(path string, track Track, entry int, start time.Duration) (*source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(f, header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	c, ok := sniffCodec(header)
+	if !ok {
+		f.Close()
+		return nil, fmt.Errorf("unsupported audio file %s", path)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	src := &source{track: track, entry: entry, codec: c, seekable: true}
+	if err := src.decode(f); err != nil {
+		return nil, err
+	}
+	if start > 0 {
+		if err := src.streamer.Seek(src.format.SampleRate.N(start)); err != nil {
+			src.close()
+			return nil, err
+		}
+	}
+	return src, nil
+}
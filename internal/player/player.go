@@ -1,14 +1,19 @@
 package player
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gopxl/beep"
-	"github.com/gopxl/beep/mp3"
+	"github.com/gopxl/beep/effects"
 	"github.com/gopxl/beep/speaker"
 )
 
@@ -20,36 +25,91 @@ const (
 	StatePaused
 )
 
+func (s State) String() string {
+	switch s {
+	case StatePlaying:
+		return "playing"
+	case StatePaused:
+		return "paused"
+	default:
+		return "stopped"
+	}
+}
+
+// prefetchWindow is how long before the end of the current track the next
+// queue entry is opened, so it is ready to be spliced in when the track ends.
+const prefetchWindow = 20 * time.Second
+
 type Track struct {
-	ID       string
-	Name     string
-	Artist   string
-	Album    string
-	Duration time.Duration
-	URL      string
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Artist   string        `json:"artist"`
+	Album    string        `json:"album"`
+	AlbumID  string        `json:"album_id,omitempty"`
+	Duration time.Duration `json:"duration"`
+	URL      string        `json:"-"`
+
+	// PlayMethod and PlaySessionID tell the server how the track is being
+	// played. They are set when the track is opened.
+	PlayMethod    string `json:"-"`
+	PlaySessionID string `json:"-"`
+}
+
+// Play methods, as reported to the server.
+const (
+	PlayMethodDirectPlay   = "DirectPlay"
+	PlayMethodDirectStream = "DirectStream"
+	PlayMethodTranscode    = "Transcode"
+)
+
+// newPlaySessionID returns a random ID tying the server's stream of a track
+// to the playback reports about it.
+func newPlaySessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 type Player struct {
 	mu           sync.Mutex
 	state        State
 	currentTrack *Track
-	queue        []Track
-	queueIndex   int
-
-	streamer beep.StreamSeekCloser
-	httpBody io.Closer
-	ctrl     *beep.Ctrl
-	format   beep.Format
-	done     chan bool
-
-	position time.Duration
-	volume   float64
+	// queue is kept in play order. While shuffling, unshuffled holds the
+	// same entries in the order they were queued.
+	queue       []queueEntry
+	unshuffled  []queueEntry
+	queueIndex  int
+	nextEntryID int
+	shuffle     bool
+	repeat      RepeatMode
+
+	// seq, ctrl and gain stay attached to the speaker for the lifetime of the
+	// player; their fields are guarded by the speaker lock.
+	seq         *sequencer
+	ctrl        *beep.Ctrl
+	gain        *effects.Volume
+	transitions chan transition
+	quit        chan struct{}
+	prefetching bool
+	// prefetchFailed is the queue entry that could not be prefetched. It is
+	// not tried again before it is due to play.
+	prefetchFailed int
+	queueGen       int
+	seekMu         sync.Mutex
+
+	volume float64
+	muted  bool
 
 	OnStateChange func(State)
 	OnTrackChange func(*Track)
 	OnProgress    func(time.Duration, time.Duration)
 
+	// LocalFile returns the path of a downloaded copy of a track, or "" to
+	// stream it. It may be nil.
+	LocalFile func(id string) string
+
 	httpClient *http.Client
+	buffers    bufferStore
 }
 
 func New() *Player {
@@ -64,12 +124,20 @@ func New() *Player {
 		}).DialContext,
 	}
 
+	transitions := make(chan transition, 16)
+	seq := &sequencer{transitions: transitions}
+	ctrl := &beep.Ctrl{Streamer: seq, Paused: true}
+
 	return &Player{
-		state:      StateStopped,
-		queue:      make([]Track, 0),
-		queueIndex: -1,
-		done:       make(chan bool),
-		volume:     1.0,
+		state:       StateStopped,
+		queue:       make([]queueEntry, 0),
+		queueIndex:  -1,
+		seq:         seq,
+		ctrl:        ctrl,
+		gain:        &effects.Volume{Streamer: ctrl, Base: 2},
+		transitions: transitions,
+		quit:        make(chan struct{}),
+		volume:      1.0,
 		httpClient: &http.Client{
 			Transport: transport,
 			Timeout:   0,
@@ -78,78 +146,214 @@ func New() *Player {
 }
 
 func (p *Player) Init() error {
-	return speaker.Init(44100, 44100/10)
+	if err := speaker.Init(outputSampleRate, outputSampleRate.N(time.Second/10)); err != nil {
+		return err
+	}
+	speaker.Play(p.gain)
+	go p.loop()
+	return nil
 }
 
-func (p *Player) LoadTrack(track Track) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// openSource starts streaming a track and sets up its decoder.
+func (p *Player) openSource(track Track, entry int) (*source, error) {
+	return p.openSourceAt(track, entry, 0)
+}
 
-	if p.streamer != nil {
-		speaker.Clear()
-		p.streamer.Close()
+// openSourceAt starts streaming a track from start. The stream goes through
+// the read-ahead buffer, except that a non-zero start asks the server to
+// transcode the track from there.
+// A downloaded copy is played instead when there is one; if it cannot be
+// decoded the track is streamed as usual. Seeks keep the play session of
+// the track.
+func (p *Player) openSourceAt(track Track, entry int, start time.Duration) (*source, error) {
+	if track.PlaySessionID == "" {
+		track.PlaySessionID = newPlaySessionID()
 	}
-	if p.httpBody != nil {
-		p.httpBody.Close()
-		p.httpBody = nil
+
+	if p.LocalFile != nil {
+		if path := p.LocalFile(track.ID); path != "" {
+			local := track
+			local.PlayMethod = PlayMethodDirectPlay
+			if src, err := openFile(path, local, entry, start); err == nil {
+				return src, nil
+			}
+		}
 	}
 
-	resp, err := p.httpClient.Get(track.URL)
+	if start > 0 {
+		return p.openStreamAt(track, entry, start)
+	}
+
+	r, err := p.buffers.open(p.httpClient, track.URL, track.PlaySessionID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	buf := r.b
+	track.PlaySessionID = buf.session
+
+	// The server only accepts ranges when it sends the original file.
+	track.PlayMethod = PlayMethodTranscode
+	if buf.ranges {
+		track.PlayMethod = PlayMethodDirectStream
+	}
+
+	c, ok := codecForType(buf.contentType)
+	if !ok {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.Seek(0, io.SeekStart)
+		if c, ok = sniffCodec(header); !ok {
+			// Nothing can play the stream, so stop downloading it.
+			r.Close()
+			buf.close()
+			return nil, fmt.Errorf("unsupported audio stream (content type %q)", buf.contentType)
+		}
+	}
+
+	// Only decoders that seek cheaply see the reader as a seeker; others
+	// would scan the whole stream up front.
+	var body io.ReadCloser = readCloser{Reader: r, Closer: r}
+	src := &source{track: track, entry: entry, codec: c, buf: buf}
+	if buf.ranges {
+		src.size = buf.size
+		if c.seekable {
+			body = r
+			src.seekable = true
+		}
 	}
 
-	streamer, format, err := mp3.Decode(resp.Body)
+	if err := src.decode(body); err != nil {
+		// decode closed the reader. Stop the download too, unless someone
+		// else still reads it.
+		if buf.idle() {
+			buf.close()
+		}
+		return nil, err
+	}
+	return src, nil
+}
+
+// openStreamAt opens an unbuffered stream that the server starts at start.
+func (p *Player) openStreamAt(track Track, entry int, start time.Duration) (*source, error) {
+	streamURL, err := transcodeURL(track.URL, start, track.PlaySessionID)
+	if err != nil {
+		return nil, err
+	}
+	track.PlayMethod = PlayMethodTranscode
+
+	resp, err := p.httpClient.Get(streamURL)
 	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
+		return nil, fmt.Errorf("failed to open stream: %s", resp.Status)
+	}
+
+	c, body, err := detectCodec(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	src := &source{track: track, entry: entry, codec: c, offset: start}
+	if err := src.decode(body); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// transcodeURL turns a universal stream URL into one starting at start.
+// The server ignores the start of streams it sends as they are, so the URL
+// lists no container the player takes as is and the server has to transcode.
+func transcodeURL(streamURL string, start time.Duration, session string) (string, error) {
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Del("Container")
+	q.Set("StartTimeTicks", strconv.FormatInt(int64(start/100), 10))
+	q.Set("PlaySessionId", session)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (p *Player) LoadTrack(track Track) error {
+	p.mu.Lock()
+	entry := p.currentEntryID()
+	p.mu.Unlock()
+
+	src, err := p.openSource(track, entry)
+	if err != nil {
 		return err
 	}
+	p.setSource(src)
+	return nil
+}
+
+// setSource makes src the current source in a stopped state, discarding
+// whatever was playing or prefetched before.
+func (p *Player) setSource(src *source) {
+	p.mu.Lock()
+	speaker.Lock()
+	old, oldNext := p.seq.current, p.seq.next
+	p.seq.current, p.seq.next = src, nil
+	p.ctrl.Paused = true
+	speaker.Unlock()
 
-	p.streamer = streamer
-	p.httpBody = resp.Body
-	p.format = format
-	p.currentTrack = &track
+	p.queueGen++
+	p.prefetching = false
+	p.currentTrack = &src.track
+	wasActive := p.state != StateStopped
 	p.state = StateStopped
-	p.position = 0
+	p.mu.Unlock()
+
+	old.close()
+	oldNext.close()
 
+	if wasActive && p.OnStateChange != nil {
+		p.OnStateChange(StateStopped)
+	}
 	if p.OnTrackChange != nil {
-		p.OnTrackChange(&track)
+		p.OnTrackChange(&src.track)
 	}
-
-	return nil
 }
 
 func (p *Player) Play() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.streamer == nil {
+	if p.state == StatePlaying {
 		return
 	}
 
-	if p.state == StatePlaying {
+	speaker.Lock()
+	hasSource := p.seq.current != nil
+	if hasSource {
+		p.ctrl.Paused = false
+	}
+	speaker.Unlock()
+
+	if !hasSource {
 		return
 	}
 
-	p.ctrl = &beep.Ctrl{Streamer: p.streamer, Paused: false}
 	p.state = StatePlaying
 
-	speaker.Play(beep.Seq(p.ctrl, beep.Callback(func() {
-		p.done <- true
-	})))
-
 	if p.OnStateChange != nil {
 		p.OnStateChange(p.state)
 	}
-
-	go p.trackProgress()
 }
 
 func (p *Player) Pause() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.ctrl == nil || p.state != StatePlaying {
+	if p.state != StatePlaying {
 		return
 	}
 
@@ -168,7 +372,7 @@ func (p *Player) Resume() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.ctrl == nil || p.state != StatePaused {
+	if p.state != StatePaused {
 		return
 	}
 
@@ -184,79 +388,54 @@ func (p *Player) Resume() {
 }
 
 func (p *Player) TogglePause() {
-	if p.state == StatePlaying {
+	switch p.GetState() {
+	case StatePlaying:
 		p.Pause()
-	} else if p.state == StatePaused {
+	case StatePaused:
 		p.Resume()
-	} else if p.currentTrack != nil {
-		p.Play()
+	default:
+		if p.GetCurrentTrack() != nil {
+			p.Play()
+		}
 	}
 }
 
 func (p *Player) Stop() {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	speaker.Lock()
+	cur, next := p.seq.current, p.seq.next
+	p.seq.current, p.seq.next = nil, nil
+	p.ctrl.Paused = true
+	speaker.Unlock()
 
-	speaker.Clear()
-	if p.streamer != nil {
-		p.streamer.Close()
-		p.streamer = nil
-	}
-	if p.httpBody != nil {
-		p.httpBody.Close()
-		p.httpBody = nil
-	}
-	p.ctrl = nil
+	p.queueGen++
+	p.prefetching = false
 	p.state = StateStopped
-	p.position = 0
+	p.mu.Unlock()
+
+	cur.close()
+	next.close()
 
 	if p.OnStateChange != nil {
-		p.OnStateChange(p.state)
+		p.OnStateChange(StateStopped)
 	}
 }
 
-func (p *Player) SetQueue(tracks []Track) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.queue = tracks
-	p.queueIndex = -1
-}
-
-func (p *Player) PlayFromQueue(index int) error {
-	p.mu.Lock()
-	if index < 0 || index >= len(p.queue) {
-		p.mu.Unlock()
-		return nil
-	}
-	p.queueIndex = index
-	track := p.queue[index]
-	p.mu.Unlock()
-
-	if err := p.LoadTrack(track); err != nil {
-		return err
-	}
-	p.Play()
-	return nil
-}
+// Reset stops playback and empties the queue, leaving the volume and play
+// modes alone.
+func (p *Player) Reset() {
+	p.Stop()
 
-func (p *Player) Next() error {
 	p.mu.Lock()
-	nextIndex := p.queueIndex + 1
-	if nextIndex >= len(p.queue) {
-		nextIndex = 0
-	}
+	p.queue = nil
+	p.unshuffled = nil
+	p.queueIndex = -1
+	p.currentTrack = nil
 	p.mu.Unlock()
-	return p.PlayFromQueue(nextIndex)
-}
 
-func (p *Player) Previous() error {
-	p.mu.Lock()
-	prevIndex := p.queueIndex - 1
-	if prevIndex < 0 {
-		prevIndex = len(p.queue) - 1
+	if p.OnTrackChange != nil {
+		p.OnTrackChange(nil)
 	}
-	p.mu.Unlock()
-	return p.PlayFromQueue(prevIndex)
 }
 
 func (p *Player) GetState() State {
@@ -278,41 +457,201 @@ func (p *Player) GetQueueIndex() int {
 }
 
 func (p *Player) GetPosition() time.Duration {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	if p.streamer == nil {
+	speaker.Lock()
+	defer speaker.Unlock()
+	cur := p.seq.current
+	if cur == nil {
 		return 0
 	}
-	return p.format.SampleRate.D(p.streamer.Position())
+	return cur.position()
 }
 
 func (p *Player) GetDuration() time.Duration {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	if p.streamer == nil {
+	speaker.Lock()
+	defer speaker.Unlock()
+	cur := p.seq.current
+	if cur == nil {
 		return 0
 	}
-	return p.format.SampleRate.D(p.streamer.Len())
+	if cur.track.Duration > 0 {
+		return cur.track.Duration
+	}
+	return cur.format.SampleRate.D(cur.streamer.Len())
 }
 
-func (p *Player) trackProgress() {
+// loop reports progress, prefetches the upcoming track and follows the
+// sequencer as it moves from one track to the next.
+func (p *Player) loop() {
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
 	for {
 		select {
+		case t := <-p.transitions:
+			p.advance(t)
 		case <-ticker.C:
-			if p.GetState() == StatePlaying && p.OnProgress != nil {
-				p.OnProgress(p.GetPosition(), p.GetDuration())
+			if p.GetState() != StatePlaying {
+				continue
 			}
-		case <-p.done:
-			p.Next()
+			pos, dur := p.GetPosition(), p.GetDuration()
+			if dur-pos <= prefetchWindow {
+				p.prefetch()
+			}
+			if p.OnProgress != nil {
+				p.OnProgress(pos, dur)
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// advance updates the player after the sequencer finished a track.
+func (p *Player) advance(t transition) {
+	t.finished.close()
+
+	p.mu.Lock()
+	speaker.Lock()
+	stale := p.seq.current != t.next
+	if t.next == nil && !stale {
+		p.ctrl.Paused = true
+	}
+	speaker.Unlock()
+
+	if stale {
+		// The track was replaced while the transition was in flight.
+		p.mu.Unlock()
+		return
+	}
+
+	p.prefetching = false
+	if t.next == nil {
+		if next := p.nextIndex(true); next >= 0 {
+			// Nothing was prefetched in time: open the next entry now.
+			gen := p.queueGen
+			p.mu.Unlock()
+			go p.continueAt(gen, next)
+			return
+		}
+		p.state = StateStopped
+		p.mu.Unlock()
+		if p.OnStateChange != nil {
+			p.OnStateChange(StateStopped)
+		}
+		return
+	}
+
+	p.queueIndex = p.entryIndex(t.next.entry)
+	p.currentTrack = &t.next.track
+	p.mu.Unlock()
+
+	if p.OnTrackChange != nil {
+		p.OnTrackChange(&t.next.track)
+	}
+}
+
+// continueAt plays the queue entry at index after the previous track ran
+// out, unless the queue changed meanwhile. Playback stops if it cannot be
+// opened.
+func (p *Player) continueAt(gen, index int) {
+	p.mu.Lock()
+	stale := gen != p.queueGen
+	p.mu.Unlock()
+	if stale {
+		return
+	}
+	if err := p.PlayFromQueue(index); err != nil {
+		p.Stop()
+	}
+}
+
+// prefetch opens the next queue entry in the background and hands it to the
+// sequencer so playback continues without a gap.
+func (p *Player) prefetch() {
+	p.mu.Lock()
+	speaker.Lock()
+	ready := p.seq.next != nil
+	current := p.seq.current
+	speaker.Unlock()
+
+	index := p.nextIndex(true)
+	if ready || p.prefetching || current == nil || index < 0 || p.queue[index].id == p.prefetchFailed {
+		p.mu.Unlock()
+		return
+	}
+	p.prefetching = true
+	gen := p.queueGen
+	entry := p.queue[index]
+	p.mu.Unlock()
+
+	go func() {
+		src, err := p.openSource(entry.track, entry.id)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if gen != p.queueGen {
+			src.close()
 			return
 		}
+		p.prefetching = false
+		if err != nil {
+			p.prefetchFailed = entry.id
+			return
+		}
+
+		speaker.Lock()
+		if p.seq.current == current && p.seq.next == nil {
+			p.seq.next = src
+			src = nil
+		}
+		speaker.Unlock()
+		src.close()
+	}()
+}
+
+// takePrefetched returns the prefetched source if it is for the given queue
+// entry, removing it from the sequencer.
+func (p *Player) takePrefetched(entry int) *source {
+	speaker.Lock()
+	defer speaker.Unlock()
+	next := p.seq.next
+	if next == nil || next.entry != entry {
+		return nil
 	}
+	p.seq.next = nil
+	return next
+}
+
+// dropPrefetched discards the prefetched source after the queue changed.
+func (p *Player) dropPrefetched() {
+	p.mu.Lock()
+	p.queueGen++
+	p.prefetching = false
+	p.mu.Unlock()
+
+	speaker.Lock()
+	next := p.seq.next
+	p.seq.next = nil
+	speaker.Unlock()
+	next.close()
 }
 
 func (p *Player) Close() {
+	close(p.quit)
 	p.Stop()
 	speaker.Close()
+	p.buffers.closeAll()
+}
+
+// Buffered returns how much of the current track has been downloaded, from
+// 0 to 1, or -1 when that is unknown or the track is not streamed through
+// the read-ahead buffer.
+func (p *Player) Buffered() float64 {
+	speaker.Lock()
+	cur := p.seq.current
+	speaker.Unlock()
+	if cur == nil || cur.buf == nil {
+		return -1
+	}
+	return cur.buf.level()
 }
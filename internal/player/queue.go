@@ -0,0 +1,366 @@
+package player
+
+import (
+	"errors"
+	"math/rand"
+)
+
+var (
+	ErrQueueIndex    = errors.New("queue index out of range")
+	ErrRemovePlaying = errors.New("cannot remove the playing track")
+)
+
+// queueEntry is a track in the play queue. The id tells repeated occurrences
+// of a track apart and stays the same when the queue is reordered.
+type queueEntry struct {
+	id    int
+	track Track
+}
+
+type RepeatMode int
+
+const (
+	RepeatOff RepeatMode = iota
+	RepeatAll
+	RepeatOne
+)
+
+func (r RepeatMode) String() string {
+	switch r {
+	case RepeatAll:
+		return "all"
+	case RepeatOne:
+		return "one"
+	default:
+		return "off"
+	}
+}
+
+func (p *Player) SetQueue(tracks []Track) {
+	p.mu.Lock()
+	p.queue = p.newEntries(tracks)
+	p.unshuffled = nil
+	p.queueIndex = -1
+	p.mu.Unlock()
+	p.dropPrefetched()
+}
+
+// PlayTracks replaces the queue with tracks and starts playing at start.
+func (p *Player) PlayTracks(tracks []Track, start int) error {
+	p.SetQueue(tracks)
+	return p.PlayFromQueue(start)
+}
+
+func (p *Player) PlayFromQueue(index int) error {
+	p.mu.Lock()
+	if index < 0 || index >= len(p.queue) {
+		p.mu.Unlock()
+		return nil
+	}
+	if p.shuffle && p.unshuffled == nil {
+		// Starting a fresh queue: shuffle the rest around the chosen track.
+		index = p.shuffleAround(index)
+	}
+	p.queueIndex = index
+	entry := p.queue[index]
+	p.mu.Unlock()
+
+	src := p.takePrefetched(entry.id)
+	if src == nil {
+		var err error
+		if src, err = p.openSource(entry.track, entry.id); err != nil {
+			return err
+		}
+	}
+	p.setSource(src)
+	p.Play()
+	return nil
+}
+
+// Next skips to the next track in the queue. At the end of the queue with
+// repeat off playback stops.
+func (p *Player) Next() error {
+	p.mu.Lock()
+	nextIndex := p.nextIndex(false)
+	p.mu.Unlock()
+	if nextIndex < 0 {
+		p.Stop()
+		return nil
+	}
+	return p.PlayFromQueue(nextIndex)
+}
+
+// Previous goes back to the previous track in the queue, or restarts the
+// current track when there is none.
+func (p *Player) Previous() error {
+	p.mu.Lock()
+	prevIndex := p.prevIndex()
+	p.mu.Unlock()
+	if prevIndex < 0 {
+		return p.Seek(0)
+	}
+	return p.PlayFromQueue(prevIndex)
+}
+
+// Queue returns the queued tracks in play order and the index of the
+// current one, or -1 if none is playing.
+func (p *Player) Queue() ([]Track, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tracks := make([]Track, len(p.queue))
+	for i, e := range p.queue {
+		tracks[i] = e.track
+	}
+	return tracks, p.queueIndex
+}
+
+// Enqueue adds tracks to the end of the queue.
+func (p *Player) Enqueue(tracks ...Track) {
+	p.mu.Lock()
+	entries := p.newEntries(tracks)
+	p.queue = append(p.queue, entries...)
+	if p.unshuffled != nil {
+		p.unshuffled = append(p.unshuffled, entries...)
+	}
+	p.mu.Unlock()
+	p.dropPrefetched()
+}
+
+// EnqueueNext inserts tracks right after the current one.
+func (p *Player) EnqueueNext(tracks ...Track) {
+	p.mu.Lock()
+	entries := p.newEntries(tracks)
+	if p.unshuffled != nil {
+		at := p.entryPosition(p.unshuffled, p.currentEntryID()) + 1
+		p.unshuffled = insertEntries(p.unshuffled, at, entries)
+	}
+	p.queue = insertEntries(p.queue, p.queueIndex+1, entries)
+	p.mu.Unlock()
+	p.dropPrefetched()
+}
+
+// MoveInQueue moves the entry at index from to index to.
+func (p *Player) MoveInQueue(from, to int) error {
+	p.mu.Lock()
+	if from < 0 || from >= len(p.queue) || to < 0 || to >= len(p.queue) {
+		p.mu.Unlock()
+		return ErrQueueIndex
+	}
+
+	entry := p.queue[from]
+	p.queue = append(p.queue[:from], p.queue[from+1:]...)
+	p.queue = insertEntries(p.queue, to, []queueEntry{entry})
+
+	switch {
+	case from == p.queueIndex:
+		p.queueIndex = to
+	case from < p.queueIndex && to >= p.queueIndex:
+		p.queueIndex--
+	case from > p.queueIndex && to <= p.queueIndex:
+		p.queueIndex++
+	}
+	p.mu.Unlock()
+	p.dropPrefetched()
+	return nil
+}
+
+// RemoveFromQueue removes the entry at index. The playing track cannot be
+// removed.
+func (p *Player) RemoveFromQueue(index int) error {
+	p.mu.Lock()
+	if index < 0 || index >= len(p.queue) {
+		p.mu.Unlock()
+		return ErrQueueIndex
+	}
+	if index == p.queueIndex {
+		p.mu.Unlock()
+		return ErrRemovePlaying
+	}
+
+	id := p.queue[index].id
+	p.queue = append(p.queue[:index], p.queue[index+1:]...)
+	if index < p.queueIndex {
+		p.queueIndex--
+	}
+	if p.unshuffled != nil {
+		if at := p.entryPosition(p.unshuffled, id); at >= 0 {
+			p.unshuffled = append(p.unshuffled[:at], p.unshuffled[at+1:]...)
+		}
+	}
+	p.mu.Unlock()
+	p.dropPrefetched()
+	return nil
+}
+
+// ClearQueue removes every entry except the playing track.
+func (p *Player) ClearQueue() {
+	p.mu.Lock()
+	if p.queueIndex >= 0 {
+		p.queue = []queueEntry{p.queue[p.queueIndex]}
+		p.queueIndex = 0
+	} else {
+		p.queue = nil
+	}
+	if p.unshuffled != nil {
+		p.unshuffled = append([]queueEntry(nil), p.queue...)
+	}
+	p.mu.Unlock()
+	p.dropPrefetched()
+}
+
+// nextIndex returns the queue entry that follows the current one, or -1 if
+// playback should stop. auto is true when the current track ended by
+// itself, which is the only case where repeat one applies. It must be
+// called with p.mu held.
+func (p *Player) nextIndex(auto bool) int {
+	if len(p.queue) == 0 {
+		return -1
+	}
+	if auto && p.repeat == RepeatOne && p.queueIndex >= 0 {
+		return p.queueIndex
+	}
+	next := p.queueIndex + 1
+	if next >= len(p.queue) {
+		if p.repeat == RepeatOff {
+			return -1
+		}
+		next = 0
+	}
+	return next
+}
+
+// prevIndex returns the queue entry before the current one, or -1 if there
+// is none. It must be called with p.mu held.
+func (p *Player) prevIndex() int {
+	if len(p.queue) == 0 {
+		return -1
+	}
+	prev := p.queueIndex - 1
+	if prev < 0 {
+		if p.repeat == RepeatOff {
+			return -1
+		}
+		prev = len(p.queue) - 1
+	}
+	return prev
+}
+
+// newEntries wraps tracks in queue entries with fresh ids. It must be called
+// with p.mu held.
+func (p *Player) newEntries(tracks []Track) []queueEntry {
+	entries := make([]queueEntry, len(tracks))
+	for i, t := range tracks {
+		p.nextEntryID++
+		entries[i] = queueEntry{id: p.nextEntryID, track: t}
+	}
+	return entries
+}
+
+// currentEntryID returns the id of the playing entry, or -1. It must be
+// called with p.mu held.
+func (p *Player) currentEntryID() int {
+	if p.queueIndex < 0 || p.queueIndex >= len(p.queue) {
+		return -1
+	}
+	return p.queue[p.queueIndex].id
+}
+
+// entryIndex returns the position of the entry with the given id in the
+// queue, or -1. It must be called with p.mu held.
+func (p *Player) entryIndex(id int) int {
+	return p.entryPosition(p.queue, id)
+}
+
+func (p *Player) entryPosition(entries []queueEntry, id int) int {
+	for i, e := range entries {
+		if e.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertEntries(entries []queueEntry, at int, inserted []queueEntry) []queueEntry {
+	if at < 0 {
+		at = 0
+	}
+	if at > len(entries) {
+		at = len(entries)
+	}
+	out := make([]queueEntry, 0, len(entries)+len(inserted))
+	out = append(out, entries[:at]...)
+	out = append(out, inserted...)
+	return append(out, entries[at:]...)
+}
+
+// shuffleAround moves the entry at index to the front and shuffles the rest
+// behind it, remembering the original order. It returns the new index of
+// the entry, which is always 0. It must be called with p.mu held.
+func (p *Player) shuffleAround(index int) int {
+	p.unshuffled = append([]queueEntry(nil), p.queue...)
+
+	rest := make([]queueEntry, 0, len(p.queue)-1)
+	rest = append(rest, p.queue[:index]...)
+	rest = append(rest, p.queue[index+1:]...)
+	rand.Shuffle(len(rest), func(i, j int) {
+		rest[i], rest[j] = rest[j], rest[i]
+	})
+
+	p.queue = append([]queueEntry{p.queue[index]}, rest...)
+	return 0
+}
+
+// SetShuffle turns shuffle on or off. The current track keeps playing and
+// the shuffled order stays the same until the queue is replaced. Turning
+// shuffle off returns to the order the tracks were queued in.
+func (p *Player) SetShuffle(on bool) {
+	p.mu.Lock()
+	if p.shuffle == on {
+		p.mu.Unlock()
+		return
+	}
+	p.shuffle = on
+	if on && p.queueIndex >= 0 {
+		p.queueIndex = p.shuffleAround(p.queueIndex)
+	}
+	if !on && p.unshuffled != nil {
+		current := p.currentEntryID()
+		p.queue = p.unshuffled
+		p.unshuffled = nil
+		p.queueIndex = p.entryIndex(current)
+	}
+	p.mu.Unlock()
+	p.dropPrefetched()
+}
+
+func (p *Player) ToggleShuffle() bool {
+	on := !p.Shuffle()
+	p.SetShuffle(on)
+	return on
+}
+
+func (p *Player) Shuffle() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.shuffle
+}
+
+func (p *Player) SetRepeat(mode RepeatMode) {
+	p.mu.Lock()
+	p.repeat = mode
+	p.mu.Unlock()
+	p.dropPrefetched()
+}
+
+// CycleRepeat switches to the next repeat mode (off, all, one) and returns it.
+func (p *Player) CycleRepeat() RepeatMode {
+	mode := (p.Repeat() + 1) % 3
+	p.SetRepeat(mode)
+	return mode
+}
+
+func (p *Player) Repeat() RepeatMode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.repeat
+}
@@ -0,0 +1,179 @@
+package player
+
+import (
+	"fmt"
+	"testing"
+)
+
+// queued returns a player whose queue holds n tracks named t0 to t(n-1),
+// with the one at index playing.
+func queued(n, index int) *Player {
+	p := New()
+	tracks := make([]Track, n)
+	for i := range tracks {
+		tracks[i] = Track{ID: fmt.Sprintf("t%d", i)}
+	}
+	p.SetQueue(tracks)
+	p.queueIndex = index
+	return p
+}
+
+// order returns the track ids of the queue in play order.
+func order(p *Player) []string {
+	tracks, _ := p.Queue()
+	ids := make([]string, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+func TestNextIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      int
+		index  int
+		repeat RepeatMode
+		auto   bool
+		want   int
+	}{
+		{"empty", 0, -1, RepeatAll, false, -1},
+		{"nothing playing", 3, -1, RepeatOff, false, 0},
+		{"middle", 3, 1, RepeatOff, false, 2},
+		{"last, repeat off", 3, 2, RepeatOff, false, -1},
+		{"last, repeat all", 3, 2, RepeatAll, false, 0},
+		{"last, repeat one skipped", 3, 2, RepeatOne, false, 0},
+		{"repeat one at track end", 3, 1, RepeatOne, true, 1},
+		{"repeat one, nothing playing", 3, -1, RepeatOne, true, 0},
+		{"repeat all at track end", 3, 1, RepeatAll, true, 2},
+		{"single track, repeat all", 1, 0, RepeatAll, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := queued(tt.n, tt.index)
+			p.repeat = tt.repeat
+			if got := p.nextIndex(tt.auto); got != tt.want {
+				t.Errorf("nextIndex(%v) = %d, want %d", tt.auto, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrevIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      int
+		index  int
+		repeat RepeatMode
+		want   int
+	}{
+		{"empty", 0, -1, RepeatAll, -1},
+		{"middle", 3, 1, RepeatOff, 0},
+		{"first, repeat off", 3, 0, RepeatOff, -1},
+		{"first, repeat all", 3, 0, RepeatAll, 2},
+		{"first, repeat one", 3, 0, RepeatOne, 2},
+		{"last", 3, 2, RepeatOff, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := queued(tt.n, tt.index)
+			p.repeat = tt.repeat
+			if got := p.prevIndex(); got != tt.want {
+				t.Errorf("prevIndex() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShuffleAround(t *testing.T) {
+	for _, index := range []int{0, 3, 7} {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			p := queued(8, index)
+			before := order(p)
+
+			p.SetShuffle(true)
+			after := order(p)
+			if p.queueIndex != 0 || after[0] != before[index] {
+				t.Fatalf("playing %d (%s), want 0 (%s)", p.queueIndex, after[0], before[index])
+			}
+			seen := map[string]bool{}
+			for _, id := range after {
+				seen[id] = true
+			}
+			if len(after) != len(before) || len(seen) != len(before) {
+				t.Fatalf("shuffled queue %v is not a permutation of %v", after, before)
+			}
+
+			p.SetShuffle(false)
+			if got := order(p); fmt.Sprint(got) != fmt.Sprint(before) {
+				t.Errorf("unshuffled queue = %v, want %v", got, before)
+			}
+			if p.queueIndex != index {
+				t.Errorf("unshuffled index = %d, want %d", p.queueIndex, index)
+			}
+		})
+	}
+}
+
+func TestShuffleKeepsQueueEdits(t *testing.T) {
+	p := queued(4, 1)
+	p.SetShuffle(true)
+	p.Enqueue(Track{ID: "end"})
+	p.EnqueueNext(Track{ID: "next"})
+	// The queue is now t1, next, three shuffled tracks and end.
+	if err := p.RemoveFromQueue(3); err != nil {
+		t.Fatal(err)
+	}
+	p.SetShuffle(false)
+
+	got := order(p)
+	if len(got) != 5 {
+		t.Fatalf("unshuffled queue = %v, want 5 tracks", got)
+	}
+	if got[p.queueIndex] != "t1" {
+		t.Errorf("playing %s after unshuffling, want t1", got[p.queueIndex])
+	}
+	if got[p.queueIndex+1] != "next" {
+		t.Errorf("queue after unshuffling = %v, want next after t1", got)
+	}
+	if got[len(got)-1] != "end" {
+		t.Errorf("queue after unshuffling = %v, want end last", got)
+	}
+}
+
+func TestMoveInQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		index     int
+		from, to  int
+		want      string
+		wantIndex int
+		wantErr   error
+	}{
+		{"forward past playing", 2, 1, 3, "[t0 t2 t3 t1 t4]", 1, nil},
+		{"backward past playing", 2, 4, 0, "[t4 t0 t1 t2 t3]", 3, nil},
+		{"playing forward", 2, 2, 4, "[t0 t1 t3 t4 t2]", 4, nil},
+		{"playing backward", 2, 2, 0, "[t2 t0 t1 t3 t4]", 0, nil},
+		{"onto playing from before", 2, 0, 2, "[t1 t2 t0 t3 t4]", 1, nil},
+		{"onto playing from after", 2, 4, 2, "[t0 t1 t4 t2 t3]", 3, nil},
+		{"behind playing", 2, 3, 4, "[t0 t1 t2 t4 t3]", 2, nil},
+		{"nothing playing", -1, 0, 4, "[t1 t2 t3 t4 t0]", -1, nil},
+		{"same place", 2, 1, 1, "[t0 t1 t2 t3 t4]", 2, nil},
+		{"from out of range", 2, 5, 0, "[t0 t1 t2 t3 t4]", 2, ErrQueueIndex},
+		{"to out of range", 2, 0, -1, "[t0 t1 t2 t3 t4]", 2, ErrQueueIndex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := queued(5, tt.index)
+			if err := p.MoveInQueue(tt.from, tt.to); err != tt.wantErr {
+				t.Fatalf("MoveInQueue(%d, %d) = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+			}
+			if got := fmt.Sprint(order(p)); got != tt.want {
+				t.Errorf("queue = %s, want %s", got, tt.want)
+			}
+			if p.queueIndex != tt.wantIndex {
+				t.Errorf("playing index = %d, want %d", p.queueIndex, tt.wantIndex)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package player
+
+import (
+	"io"
+	"time"
+
+	"github.com/gopxl/beep/speaker"
+)
+
+// Seek moves playback of the current track to pos. The position is reached
+// by opening a new stream so the old one keeps playing until it is ready.
+func (p *Player) Seek(pos time.Duration) error {
+	p.seekMu.Lock()
+	defer p.seekMu.Unlock()
+	return p.seek(pos)
+}
+
+// SeekRelative moves playback of the current track by delta.
+func (p *Player) SeekRelative(delta time.Duration) error {
+	p.seekMu.Lock()
+	defer p.seekMu.Unlock()
+	return p.seek(p.GetPosition() + delta)
+}
+
+func (p *Player) seek(pos time.Duration) error {
+	speaker.Lock()
+	cur := p.seq.current
+	speaker.Unlock()
+	if cur == nil {
+		return nil
+	}
+
+	if pos < 0 {
+		pos = 0
+	}
+	if cur.track.Duration > 0 && pos > cur.track.Duration {
+		pos = cur.track.Duration
+	}
+
+	src, err := p.openSeeked(cur, pos)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	speaker.Lock()
+	stale := p.seq.current != cur
+	if !stale {
+		p.seq.current = src
+	}
+	speaker.Unlock()
+	p.mu.Unlock()
+
+	if stale {
+		// The track changed while seeking.
+		src.close()
+		return nil
+	}
+	cur.close()
+	return nil
+}
+
+// openSeeked opens a new stream for the track played by cur, positioned at pos.
+func (p *Player) openSeeked(cur *source, pos time.Duration) (*source, error) {
+	switch {
+	case cur.seekable:
+		src, err := p.openSource(cur.track, cur.entry)
+		if err != nil {
+			return nil, err
+		}
+		if err := src.streamer.Seek(src.format.SampleRate.N(pos)); err != nil {
+			src.close()
+			return nil, err
+		}
+		return src, nil
+
+	case cur.size > 0 && cur.codec.resyncs && cur.track.Duration > 0:
+		// Estimate the byte offset and let the decoder find the next frame.
+		// A size is only known for buffered streams.
+		offset := int64(float64(cur.size) * float64(pos) / float64(cur.track.Duration))
+		r := cur.buf.newReader()
+		r.Seek(offset, io.SeekStart)
+		body := readCloser{Reader: r, Closer: r}
+		src := &source{
+			track:  cur.track,
+			entry:  cur.entry,
+			codec:  cur.codec,
+			buf:    cur.buf,
+			size:   cur.size,
+			offset: pos,
+		}
+		if err := src.decode(body); err != nil {
+			return nil, err
+		}
+		return src, nil
+
+	default:
+		return p.openSourceAt(cur.track, cur.entry, pos)
+	}
+}
@@ -0,0 +1,95 @@
+package player
+
+import (
+	"io"
+	"time"
+
+	"github.com/gopxl/beep"
+)
+
+// source is a track that has been opened and is ready to be fed to the speaker.
+type source struct {
+	track    Track
+	entry    int
+	streamer beep.StreamSeekCloser
+	format   beep.Format
+	output   beep.Streamer
+	codec    codec
+
+	// buf is the read-ahead buffer the track is read from, if any.
+	buf *buffer
+	// size is set when the server serves the original file and accepts
+	// range requests; seekable is set when the decoder can seek in the
+	// buffer.
+	size     int64
+	seekable bool
+	// offset is where in the track the stream started.
+	offset time.Duration
+}
+
+func (s *source) decode(body io.ReadCloser) error {
+	streamer, format, err := s.codec.decode(body)
+	if err != nil {
+		body.Close()
+		return err
+	}
+	s.streamer = streamer
+	s.format = format
+	s.output = resampled(streamer, format)
+	return nil
+}
+
+// position returns the playback position within the track.
+func (s *source) position() time.Duration {
+	return s.offset + s.format.SampleRate.D(s.streamer.Position())
+}
+
+func (s *source) close() {
+	if s == nil {
+		return
+	}
+	s.streamer.Close()
+}
+
+// transition is sent by the sequencer when a track runs out. next is nil when
+// there was nothing queued to take over.
+type transition struct {
+	finished *source
+	next     *source
+}
+
+// sequencer is the streamer attached to the speaker. It plays the current
+// source and, on the exact sample where it ends, continues with the next one
+// so consecutive tracks play without a gap. It outputs silence when idle.
+type sequencer struct {
+	current     *source
+	next        *source
+	transitions chan<- transition
+}
+
+func (s *sequencer) Stream(samples [][2]float64) (n int, ok bool) {
+	for n < len(samples) && s.current != nil {
+		sn, sok := s.current.output.Stream(samples[n:])
+		n += sn
+		if sok && sn > 0 {
+			continue
+		}
+
+		t := transition{finished: s.current, next: s.next}
+		s.current, s.next = s.next, nil
+		select {
+		case s.transitions <- t:
+		default:
+			// The player is not keeping up; it resyncs on the next transition.
+		}
+	}
+
+	for i := n; i < len(samples); i++ {
+		samples[i] = [2]float64{}
+	}
+	return len(samples), true
+}
+
+func (s *sequencer) Err() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package player
+
+import (
+	"time"
+
+	"github.com/gopxl/beep/speaker"
+)
+
+// Snapshot is the player state saved between runs. Track URLs are not saved
+// since they embed the access token; they must be filled in before Restore.
+// The volume is kept in the config file instead.
+type Snapshot struct {
+	Queue      []Track       `json:"queue"`
+	QueueIndex int           `json:"queue_index"`
+	Position   time.Duration `json:"position"`
+	Shuffle    bool          `json:"shuffle"`
+	// Unshuffled lists queue indices in the order they were queued, when
+	// shuffling.
+	Unshuffled []int      `json:"unshuffled,omitempty"`
+	Repeat     RepeatMode `json:"repeat"`
+}
+
+// Snapshot captures the queue, position and play modes.
+func (p *Player) Snapshot() Snapshot {
+	position := p.GetPosition()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap := Snapshot{
+		Queue:      make([]Track, len(p.queue)),
+		QueueIndex: p.queueIndex,
+		Position:   position,
+		Shuffle:    p.shuffle,
+		Repeat:     p.repeat,
+	}
+	for i, e := range p.queue {
+		snap.Queue[i] = e.track
+	}
+	if p.unshuffled != nil {
+		snap.Unshuffled = make([]int, 0, len(p.unshuffled))
+		for _, e := range p.unshuffled {
+			if i := p.entryIndex(e.id); i >= 0 {
+				snap.Unshuffled = append(snap.Unshuffled, i)
+			}
+		}
+	}
+	return snap
+}
+
+// Restore brings back a saved snapshot. The current track is loaded paused
+// at the saved position, so resuming continues where playback left off.
+func (p *Player) Restore(snap Snapshot) error {
+	p.mu.Lock()
+	p.queue = p.newEntries(snap.Queue)
+	p.unshuffled = nil
+	if snap.Shuffle && len(snap.Unshuffled) == len(p.queue) {
+		p.unshuffled = make([]queueEntry, len(snap.Unshuffled))
+		for i, index := range snap.Unshuffled {
+			if index < 0 || index >= len(p.queue) {
+				p.unshuffled = nil
+				break
+			}
+			p.unshuffled[i] = p.queue[index]
+		}
+	}
+	p.queueIndex = -1
+	p.shuffle = snap.Shuffle
+	p.repeat = snap.Repeat
+
+	if snap.QueueIndex < 0 || snap.QueueIndex >= len(p.queue) {
+		p.mu.Unlock()
+		return nil
+	}
+	p.queueIndex = snap.QueueIndex
+	entry := p.queue[snap.QueueIndex]
+	p.mu.Unlock()
+
+	src, err := p.openSource(entry.track, entry.id)
+	if err != nil {
+		return err
+	}
+	if snap.Position > 0 {
+		seeked, err := p.openSeeked(src, snap.Position)
+		src.close()
+		if err != nil {
+			return err
+		}
+		src = seeked
+	}
+	p.setSource(src)
+
+	p.mu.Lock()
+	speaker.Lock()
+	p.ctrl.Paused = true
+	speaker.Unlock()
+	p.state = StatePaused
+	p.mu.Unlock()
+
+	if p.OnStateChange != nil {
+		p.OnStateChange(StatePaused)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package player
+
+import (
+	"github.com/gopxl/beep/speaker"
+)
+
+// volumeRange is the span of the volume scale in powers of two: full volume
+// plays at unity gain and each step down is perceived as roughly even.
+const volumeRange = 6.0
+
+// SetVolume sets the playback volume, from 0 (silent) to 1 (full).
+func (p *Player) SetVolume(level float64) {
+	if level < 0 {
+		level = 0
+	}
+	if level > 1 {
+		level = 1
+	}
+
+	p.mu.Lock()
+	p.volume = level
+	p.applyVolume()
+	p.mu.Unlock()
+}
+
+func (p *Player) Volume() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.volume
+}
+
+// ToggleMute mutes or unmutes playback and returns the new mute state.
+func (p *Player) ToggleMute() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.muted = !p.muted
+	p.applyVolume()
+	return p.muted
+}
+
+func (p *Player) Muted() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.muted
+}
+
+// applyVolume pushes the volume settings to the audio chain. It must be
+// called with p.mu held.
+func (p *Player) applyVolume() {
+	speaker.Lock()
+	p.gain.Volume = (p.volume - 1) * volumeRange
+	p.gain.Silent = p.muted || p.volume <= 0
+	speaker.Unlock()
+}
@@ -0,0 +1,209 @@
+// Package reporter tells the Jellyfin server what the player is doing.
+package reporter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+)
+
+const (
+	progressReportInterval = 10 * time.Second
+	reporterFlushTimeout   = 2 * time.Second
+)
+
+type reportKind int
+
+const (
+	reportStart reportKind = iota
+	reportProgress
+	reportStopped
+)
+
+type reportEvent struct {
+	client *jellyfin.Client
+	kind   reportKind
+	info   jellyfin.PlaybackInfo
+}
+
+// Reporter tells the Jellyfin server what the player is doing so the
+// session shows up in other clients and play counts get updated. Reports are
+// sent in order from a single goroutine so a slow server never blocks playback.
+type Reporter struct {
+	events chan reportEvent
+	done   chan struct{}
+	// ctx is cancelled when Close gives up on flushing the reports.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu           sync.Mutex
+	client       *jellyfin.Client
+	state        player.State
+	itemID       string
+	playMethod   string
+	playSession  string
+	started      bool
+	paused       bool
+	position     time.Duration
+	lastProgress time.Time
+	closed       bool
+}
+
+func New(client *jellyfin.Client) *Reporter {
+	r := &Reporter{
+		client: client,
+		events: make(chan reportEvent, 32),
+		done:   make(chan struct{}),
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	go r.run()
+	return r
+}
+
+func (r *Reporter) run() {
+	defer close(r.done)
+	for ev := range r.events {
+		switch ev.kind {
+		case reportStart:
+			ev.client.ReportPlaybackStart(r.ctx, ev.info)
+		case reportProgress:
+			ev.client.ReportPlaybackProgress(r.ctx, ev.info)
+		case reportStopped:
+			ev.client.ReportPlaybackStopped(r.ctx, ev.info)
+		}
+	}
+}
+
+// SetClient sends later reports through client, after switching servers.
+// Reports already queued still go to the previous server.
+func (r *Reporter) SetClient(client *jellyfin.Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.client = client
+}
+
+// TrackChanged is called when the player loads a new track. The previous
+// track, if any, is reported as stopped. When the player moved on by itself
+// the new track is already playing and is reported as started right away.
+func (r *Reporter) TrackChanged(track *player.Track) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		r.send(reportStopped, "")
+	}
+	r.itemID, r.playMethod, r.playSession = "", "", ""
+	if track != nil {
+		r.itemID = track.ID
+		r.playMethod = track.PlayMethod
+		r.playSession = track.PlaySessionID
+	}
+	r.started = false
+	r.paused = false
+	r.position = 0
+
+	if r.itemID != "" && r.state == player.StatePlaying {
+		r.started = true
+		r.send(reportStart, "")
+	}
+}
+
+func (r *Reporter) StateChanged(state player.State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state = state
+	if r.itemID == "" {
+		return
+	}
+
+	switch state {
+	case player.StatePlaying:
+		r.paused = false
+		if !r.started {
+			r.started = true
+			r.send(reportStart, "")
+		} else {
+			r.send(reportProgress, "Unpause")
+		}
+	case player.StatePaused:
+		r.paused = true
+		if !r.started {
+			r.started = true
+			r.send(reportStart, "")
+		} else {
+			r.send(reportProgress, "Pause")
+		}
+	case player.StateStopped:
+		if r.started {
+			r.send(reportStopped, "")
+		}
+		r.started = false
+		r.itemID = ""
+	}
+}
+
+func (r *Reporter) Progress(pos, dur time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.position = pos
+	if !r.started || time.Since(r.lastProgress) < progressReportInterval {
+		return
+	}
+	r.send(reportProgress, "TimeUpdate")
+}
+
+// send queues a report for the current item. It must be called with r.mu held.
+func (r *Reporter) send(kind reportKind, event string) {
+	if r.closed {
+		return
+	}
+	ev := reportEvent{
+		client: r.client,
+		kind:   kind,
+		info: jellyfin.PlaybackInfo{
+			ItemID:        r.itemID,
+			PositionTicks: jellyfin.DurationToTicks(r.position),
+			IsPaused:      r.paused,
+			CanSeek:       true,
+			PlayMethod:    r.playMethod,
+			PlaySessionID: r.playSession,
+			EventName:     event,
+		},
+	}
+	if kind == reportProgress {
+		r.lastProgress = time.Now()
+	}
+	select {
+	case r.events <- ev:
+	default:
+		// Drop the report rather than stall the player if the server is unreachable.
+	}
+}
+
+// Close reports the current track as stopped and waits briefly for pending
+// reports to reach the server.
+func (r *Reporter) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	if r.started {
+		r.send(reportStopped, "")
+		r.started = false
+	}
+	r.closed = true
+	close(r.events)
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+	case <-time.After(reporterFlushTimeout):
+	}
+	r.cancel()
+}
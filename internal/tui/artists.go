@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// artistPageSize is how many artists are loaded at a time.
+	artistPageSize = 200
+	// artistPrefetch is how close to the end of the loaded artists the
+	// cursor gets before the next page is loaded.
+	artistPrefetch = 50
+)
+
+// artistsLoadedMsg is a page of the artist list.
+type artistsLoadedMsg jellyfin.MusicItemsResponse
+
+func (m Model) loadArtists(start int) tea.Cmd {
+	return func() tea.Msg {
+		page, err := m.client.GetArtistsPage(m.ctx, jellyfin.Page{StartIndex: start, Limit: artistPageSize})
+		if err != nil {
+			return pageErrMsg{mode: browseArtists, start: start, err: err}
+		}
+		page.StartIndex = start
+		m.library.AddArtists(start, page.Items, page.TotalRecordCount)
+		return artistsLoadedMsg(*page)
+	}
+}
+
+// showArtists adds a page to the artist list. Pages that do not follow the
+// artists already listed are dropped.
+func (m *Model) showArtists(msg artistsLoadedMsg) {
+	m.artistsLoading = false
+	if msg.StartIndex != len(m.artists) {
+		return
+	}
+	m.artists = append(m.artists, msg.Items...)
+	m.artistTotal = max(msg.TotalRecordCount, len(m.artists))
+	m.rememberFavorites(msg.Items)
+
+	items := m.artistList.Items()
+	for _, a := range msg.Items {
+		items = append(items, musicItem{a})
+	}
+	m.artistList.SetItems(items)
+	m.artistList.Title = m.artistsTitle()
+}
+
+// moreArtists loads the next page of artists once the cursor nears the end
+// of those loaded. A filtered list only covers the artists loaded so far.
+func (m Model) moreArtists() (Model, tea.Cmd) {
+	if m.browse != browseArtists || m.browseMenuActive || m.artistsLoading {
+		return m, nil
+	}
+	if len(m.artists) >= m.artistTotal || m.artistList.FilterState() != list.Unfiltered {
+		return m, nil
+	}
+	if m.artistList.Index() < len(m.artists)-artistPrefetch {
+		return m, nil
+	}
+	m.artistsLoading = true
+	return m, m.loadArtists(len(m.artists))
+}
+
+func (m Model) artistsTitle() string {
+	return pagedTitle("Artists", len(m.artists), m.artistTotal)
+}
+
+// pagedTitle is the title of a list with loaded of its total items shown.
+func pagedTitle(name string, loaded, total int) string {
+	if loaded < total {
+		return fmt.Sprintf("%s (%d of %d)", name, loaded, total)
+	}
+	return fmt.Sprintf("%s (%d)", name, total)
+}
+
+// resetArtists empties the artist list before loading it from the first
+// page.
+func (m *Model) resetArtists() {
+	m.artists = nil
+	m.artistTotal = 0
+	m.artistsLoading = false
+	m.artistList.SetItems(nil)
+	m.artistList.Title = "Artists"
+}
@@ -0,0 +1,401 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// browseMode is what the left panel lists.
+type browseMode int
+
+const (
+	browseArtists browseMode = iota
+	browseAlbums
+	browseGenres
+	browseDecades
+	browseComposers
+	browsePlaylists
+	browseFavorites
+	browseOffline
+	browseModeCount
+)
+
+// browsePageSize is how many albums, genres or composers are loaded at a
+// time, and browsePrefetch how close to the end of those loaded the cursor
+// gets before the next page is loaded.
+const (
+	browsePageSize = 200
+	browsePrefetch = 50
+)
+
+// decadeType marks the decades listed in the left panel. They are not
+// server items; the ID is the first year.
+const decadeType = "Decade"
+
+func (b browseMode) String() string {
+	switch b {
+	case browseAlbums:
+		return "Albums"
+	case browseGenres:
+		return "Genres"
+	case browseDecades:
+		return "Decades"
+	case browseComposers:
+		return "Composers"
+	case browsePlaylists:
+		return "Playlists"
+	case browseFavorites:
+		return "Favorites"
+	case browseOffline:
+		return "Offline"
+	default:
+		return "Artists"
+	}
+}
+
+// browseLoadedMsg adds the items from start on to the list of a browse
+// mode, which holds total items in all.
+type browseLoadedMsg struct {
+	mode  browseMode
+	start int
+	total int
+	items []jellyfin.MusicItem
+}
+
+// browsePager tracks a browse list loaded a page at a time.
+type browsePager struct {
+	total   int
+	loading bool
+}
+
+// paged reports whether the list of mode is loaded a page at a time.
+func (b browseMode) paged() bool {
+	return b == browseAlbums || b == browseGenres || b == browseComposers
+}
+
+// browseTracksLoadedMsg fills the track panel with tracks that do not
+// belong to one album. It answers the track load numbered seq.
+type browseTracksLoadedMsg struct {
+	seq    int
+	title  string
+	tracks []jellyfin.MusicItem
+}
+
+type browseModeItem struct {
+	mode browseMode
+}
+
+func (i browseModeItem) FilterValue() string { return i.mode.String() }
+func (i browseModeItem) Title() string       { return i.mode.String() }
+func (i browseModeItem) Description() string { return "" }
+
+type browseModeDelegate struct{}
+
+func (d browseModeDelegate) Height() int                             { return 1 }
+func (d browseModeDelegate) Spacing() int                            { return 0 }
+func (d browseModeDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d browseModeDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(browseModeItem)
+	if !ok {
+		return
+	}
+	if index == m.Index() {
+		fmt.Fprint(w, selectedListItemStyle.Render("> "+i.mode.String()))
+	} else {
+		fmt.Fprint(w, listItemStyle.Render("  "+i.mode.String()))
+	}
+}
+
+func newBrowseList(title string) list.Model {
+	l := list.New([]list.Item{}, musicDelegate{}, 0, 0)
+	l.Title = title
+	l.Styles.Title = listTitleStyle
+	l.SetShowHelp(false)
+	l.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(colorPrimary)
+	l.FilterInput.Cursor.Style = lipgloss.NewStyle().Foreground(colorPrimary)
+	return l
+}
+
+func newBrowseMenu() list.Model {
+	items := make([]list.Item, browseModeCount)
+	for i := range items {
+		items[i] = browseModeItem{mode: browseMode(i)}
+	}
+	l := list.New(items, browseModeDelegate{}, 0, 0)
+	l.Title = "Browse"
+	l.Styles.Title = listTitleStyle
+	l.SetShowHelp(false)
+	l.SetFilteringEnabled(false)
+	return l
+}
+
+// browseList returns the list shown in the left panel.
+func (m *Model) browseList() *list.Model {
+	if m.browseMenuActive {
+		return &m.browseMenu
+	}
+	return m.modeList(m.browse)
+}
+
+// modeList returns the list of a browse mode.
+func (m *Model) modeList(mode browseMode) *list.Model {
+	switch mode {
+	case browseAlbums:
+		return &m.albumList
+	case browseGenres:
+		return &m.genreList
+	case browseDecades:
+		return &m.decadeList
+	case browseComposers:
+		return &m.composerList
+	case browsePlaylists:
+		return &m.playlistList
+	case browseFavorites:
+		return &m.favoriteList
+	case browseOffline:
+		return &m.offlineList
+	default:
+		return &m.artistList
+	}
+}
+
+// cycleBrowse switches the left panel to the next (delta 1) or previous
+// (delta -1) browse mode.
+func (m Model) cycleBrowse(delta int) (Model, tea.Cmd) {
+	return m.setBrowse((m.browse + browseMode(delta) + browseModeCount) % browseModeCount)
+}
+
+// openBrowseMenu lists the browse modes in the left panel to pick one.
+func (m Model) openBrowseMenu() (Model, tea.Cmd) {
+	m.browseMenu.Select(int(m.browse))
+	m.browseMenuActive = true
+	m.panelFocus = focusArtists
+	return m, nil
+}
+
+// setBrowse switches the left panel to mode, loading its list the first
+// time.
+func (m Model) setBrowse(mode browseMode) (Model, tea.Cmd) {
+	m.browse = mode
+	m.browseMenuActive = false
+	m.panelFocus = focusArtists
+	switch mode {
+	case browseFavorites:
+		cmd := m.loadFavorites()
+		return m, cmd
+	case browseOffline:
+		m.showOffline()
+	case browseAlbums, browseGenres, browseDecades, browseComposers:
+		if len(m.modeList(mode).Items()) == 0 && !m.browsePages[mode].loading {
+			cmd := m.loadBrowse(mode, 0)
+			return m, cmd
+		}
+	}
+	return m, nil
+}
+
+// loadBrowse loads the list of mode from start on: a page of albums,
+// genres or composers, or every decade.
+func (m *Model) loadBrowse(mode browseMode, start int) tea.Cmd {
+	m.browsePages[mode].loading = true
+	client, ctx := m.client, m.ctx
+	return func() tea.Msg {
+		var resp *jellyfin.MusicItemsResponse
+		var err error
+		page := jellyfin.Page{StartIndex: start, Limit: browsePageSize}
+		switch mode {
+		case browseAlbums:
+			resp, err = client.GetAllAlbumsPage(ctx, page)
+		case browseGenres:
+			resp, err = client.GetGenresPage(ctx, page)
+		case browseComposers:
+			resp, err = client.GetComposersPage(ctx, page)
+		case browseDecades:
+			var years []int
+			years, err = client.GetAlbumYears(ctx)
+			items := decades(years)
+			resp = &jellyfin.MusicItemsResponse{Items: items, TotalRecordCount: len(items)}
+		}
+		if err != nil {
+			return pageErrMsg{mode: mode, start: start, err: err}
+		}
+		return browseLoadedMsg{mode: mode, start: start, total: resp.TotalRecordCount, items: resp.Items}
+	}
+}
+
+// decades groups years, newest first, into decades.
+func decades(years []int) []jellyfin.MusicItem {
+	var items []jellyfin.MusicItem
+	for _, year := range years {
+		start := year - year%10
+		id := strconv.Itoa(start)
+		if len(items) > 0 && items[len(items)-1].ID == id {
+			continue
+		}
+		items = append(items, jellyfin.MusicItem{ID: id, Name: id + "s", Type: decadeType})
+	}
+	return items
+}
+
+// showBrowse adds a page to the list of a browse mode. Pages that do not
+// follow the items already listed are dropped.
+func (m *Model) showBrowse(msg browseLoadedMsg) {
+	pager := &m.browsePages[msg.mode]
+	pager.loading = false
+	l := m.modeList(msg.mode)
+	items := l.Items()
+	if msg.start != len(items) {
+		return
+	}
+	m.rememberFavorites(msg.items)
+	for _, it := range msg.items {
+		items = append(items, musicItem{it})
+	}
+	pager.total = max(msg.total, len(items))
+	l.SetItems(items)
+	l.Title = pagedTitle(msg.mode.String(), len(items), pager.total)
+}
+
+// moreBrowse loads the next page of the album, genre or composer list once
+// the cursor nears the end of those loaded. A filtered list only covers
+// the items loaded so far.
+func (m Model) moreBrowse() (Model, tea.Cmd) {
+	if !m.browse.paged() || m.browseMenuActive || m.browsePages[m.browse].loading {
+		return m, nil
+	}
+	l := m.modeList(m.browse)
+	loaded := len(l.Items())
+	if loaded == 0 || loaded >= m.browsePages[m.browse].total || l.FilterState() != list.Unfiltered {
+		return m, nil
+	}
+	if l.Index() < loaded-browsePrefetch {
+		return m, nil
+	}
+	cmd := m.loadBrowse(m.browse, loaded)
+	return m, cmd
+}
+
+// pageFailed lets the next scroll retry a page that failed to load. A page
+// of a list emptied since is ignored.
+func (m *Model) pageFailed(msg pageErrMsg) {
+	if msg.mode == browseArtists {
+		if msg.start != len(m.artists) {
+			return
+		}
+		m.artistsLoading = false
+	} else {
+		if msg.start != len(m.modeList(msg.mode).Items()) {
+			return
+		}
+		m.browsePages[msg.mode].loading = false
+	}
+	if !errors.Is(msg.err, context.Canceled) {
+		m.err = msg.err
+	}
+}
+
+// resetBrowse empties the album, genre, decade and composer lists so they
+// load again from the first page.
+func (m *Model) resetBrowse() {
+	for _, mode := range []browseMode{browseAlbums, browseGenres, browseDecades, browseComposers} {
+		l := m.modeList(mode)
+		l.SetItems(nil)
+		l.Title = mode.String()
+		m.browsePages[mode] = browsePager{}
+	}
+}
+
+// openBrowseItem opens the item under the cursor in the left panel.
+func (m Model) openBrowseItem() (Model, tea.Cmd) {
+	if m.browseMenuActive {
+		if item, ok := m.browseMenu.SelectedItem().(browseModeItem); ok {
+			return m.setBrowse(item.mode)
+		}
+		return m, nil
+	}
+	item, ok := m.browseList().SelectedItem().(musicItem)
+	if !ok {
+		return m, nil
+	}
+
+	switch m.browse {
+	case browsePlaylists:
+		return m.openPlaylist(item.MusicItem)
+	case browseFavorites:
+		return m.openFavorite(item.MusicItem)
+	case browseOffline:
+		return m.openOffline(item.MusicItem)
+	}
+
+	m.panelFocus = focusTracks
+	m.showQueue = false
+	switch m.browse {
+	case browseAlbums:
+		m.currentPlaylist = nil
+		m.albums = []jellyfin.MusicItem{item.MusicItem}
+		m.selectedAlbumIndex = 0
+		cmd := m.loadTracks(item.ID)
+		return m, cmd
+	case browseGenres:
+		client := m.client
+		cmd := m.loadAlbumsWith(func(ctx context.Context) ([]jellyfin.MusicItem, error) {
+			return client.GetAlbumsByGenre(ctx, item.ID)
+		})
+		return m, cmd
+	case browseDecades:
+		start, _ := strconv.Atoi(item.ID)
+		years := make([]int, 10)
+		for i := range years {
+			years[i] = start + i
+		}
+		client := m.client
+		cmd := m.loadAlbumsWith(func(ctx context.Context) ([]jellyfin.MusicItem, error) {
+			return client.GetAlbumsByYears(ctx, years)
+		})
+		return m, cmd
+	case browseComposers:
+		m.albumLoad.stop()
+		ctx, seq := m.trackLoad.start(m.ctx)
+		client := m.client
+		return m, func() tea.Msg {
+			tracks, err := client.GetTracksByComposer(ctx, item.ID)
+			if err != nil {
+				return loadErrMsg{seq: seq, err: err}
+			}
+			return browseTracksLoadedMsg{seq: seq, title: item.Name, tracks: tracks}
+		}
+	}
+	m.currentArtist = &item.MusicItem
+	cmd := m.loadAlbums(item.ID)
+	return m, cmd
+}
+
+// loadAlbumsWith fills the track panel with the albums fetch returns,
+// which h and l then step through. It replaces any album or track load
+// still running.
+func (m *Model) loadAlbumsWith(fetch func(context.Context) ([]jellyfin.MusicItem, error)) tea.Cmd {
+	m.trackLoad.stop()
+	ctx, seq := m.albumLoad.start(m.ctx)
+	return func() tea.Msg {
+		albums, err := fetch(ctx)
+		if err != nil {
+			return loadErrMsg{albums: true, seq: seq, err: err}
+		}
+		return albumsLoadedMsg{seq: seq, albums: albums}
+	}
+}
+
+func (m *Model) showBrowseTracks(msg browseTracksLoadedMsg) {
+	m.currentPlaylist = nil
+	m.albums = nil
+	m.selectedAlbumIndex = 0
+	m.setTracks(msg.tracks, msg.title)
+}
@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const favoriteMarker = " ♥"
+
+// favoritesLoadedMsg answers the track load numbered seq.
+type favoritesLoadedMsg struct {
+	seq int
+	// items holds the favorite artists followed by the favorite albums.
+	items  []jellyfin.MusicItem
+	tracks []jellyfin.MusicItem
+}
+
+type favoriteToggledMsg struct {
+	id       string
+	favorite bool
+	err      error
+}
+
+func newFavoriteList() list.Model {
+	return newBrowseList("Favorites")
+}
+
+// loadFavorites loads the favorites, replacing any album or track load
+// still running since the favorite tracks go to the track panel.
+func (m *Model) loadFavorites() tea.Cmd {
+	m.albumLoad.stop()
+	ctx, seq := m.trackLoad.start(m.ctx)
+	client := m.client
+	return func() tea.Msg {
+		msg := favoritesLoadedMsg{seq: seq}
+		for _, itemType := range []string{"MusicArtist", "MusicAlbum"} {
+			items, err := client.GetFavorites(ctx, itemType)
+			if err != nil {
+				return loadErrMsg{seq: seq, err: err}
+			}
+			msg.items = append(msg.items, items...)
+		}
+		tracks, err := client.GetFavorites(ctx, "Audio")
+		if err != nil {
+			return loadErrMsg{seq: seq, err: err}
+		}
+		msg.tracks = tracks
+		return msg
+	}
+}
+
+// showFavorites lists the favorite artists and albums and puts every
+// favorite track in the track panel, ready to be played as one queue,
+// unless something else was opened there since.
+func (m *Model) showFavorites(msg favoritesLoadedMsg) {
+	m.rememberFavorites(msg.items)
+	items := make([]list.Item, len(msg.items))
+	for i, it := range msg.items {
+		items[i] = musicItem{it}
+	}
+	m.favoriteList.SetItems(items)
+	if msg.seq != m.trackLoad.seq {
+		return
+	}
+
+	m.currentPlaylist = nil
+	m.albums = nil
+	m.selectedAlbumIndex = 0
+	m.setTracks(msg.tracks, "Favorite tracks")
+}
+
+// openFavorite opens a favorite artist or album from the left panel.
+func (m Model) openFavorite(item jellyfin.MusicItem) (Model, tea.Cmd) {
+	m.panelFocus = focusTracks
+	m.showQueue = false
+	if item.Type == "MusicAlbum" {
+		m.currentPlaylist = nil
+		m.albums = []jellyfin.MusicItem{item}
+		m.selectedAlbumIndex = 0
+		cmd := m.loadTracks(item.ID)
+		return m, cmd
+	}
+	m.currentArtist = &item
+	cmd := m.loadAlbums(item.ID)
+	return m, cmd
+}
+
+func (m *Model) rememberFavorites(items []jellyfin.MusicItem) {
+	for _, it := range items {
+		m.favorites[it.ID] = it.UserData.IsFavorite
+	}
+}
+
+// toggleFavorite flips the favorite flag of the item under the cursor, or of
+// the playing track when the focused panel is empty. The change shows
+// immediately and is undone if the server rejects it.
+func (m Model) toggleFavorite() (Model, tea.Cmd) {
+	var id, name string
+	switch {
+	case m.queueFocused():
+		if item, ok := m.queueList.SelectedItem().(queueItem); ok {
+			id, name = item.track.ID, item.track.Name
+		}
+	case m.panelFocus == focusTracks:
+		if item, ok := m.trackList.SelectedItem().(trackItem); ok {
+			id, name = item.ID, item.Name
+		}
+	default:
+		if item, ok := m.browseList().SelectedItem().(musicItem); ok && item.Type != "Playlist" && item.Type != decadeType {
+			id, name = item.ID, item.Name
+		}
+	}
+	if id == "" && m.currentTrack != nil {
+		id, name = m.currentTrack.ID, m.currentTrack.Name
+	}
+	if id == "" {
+		return m, nil
+	}
+
+	favorite := !m.favorites[id]
+	m.setFavorite(id, favorite)
+	if favorite {
+		m.notice = fmt.Sprintf("Added %s to favorites", name)
+	} else {
+		m.notice = fmt.Sprintf("Removed %s from favorites", name)
+	}
+	return m, func() tea.Msg {
+		var err error
+		if favorite {
+			err = m.client.SetFavorite(m.ctx, id)
+		} else {
+			err = m.client.UnsetFavorite(m.ctx, id)
+		}
+		return favoriteToggledMsg{id: id, favorite: favorite, err: err}
+	}
+}
+
+// setFavorite records the favorite flag of an item and updates every list
+// that shows it.
+func (m *Model) setFavorite(id string, favorite bool) {
+	m.favorites[id] = favorite
+	m.library.SetFavorite(id, favorite)
+	for i := range m.tracks {
+		if m.tracks[i].ID == id {
+			m.tracks[i].UserData.IsFavorite = favorite
+		}
+	}
+	for _, l := range []*list.Model{&m.artistList, &m.favoriteList, &m.trackList} {
+		for i, it := range l.Items() {
+			switch item := it.(type) {
+			case musicItem:
+				if item.ID == id {
+					item.UserData.IsFavorite = favorite
+					l.SetItem(i, item)
+				}
+			case trackItem:
+				if item.ID == id {
+					item.UserData.IsFavorite = favorite
+					l.SetItem(i, item)
+				}
+			}
+		}
+	}
+	m.markQueueFavorites()
+}
@@ -0,0 +1,93 @@
+package tui
+
+import (
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/cedev-1/jellyfin-mustui/internal/library"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// libraryRefreshedMsg reports the changes pulled into a library cache.
+type libraryRefreshedMsg struct {
+	cache   *library.Cache
+	artists bool
+	err     error
+}
+
+// openLibrary opens the metadata cache of the user client is logged in as.
+// Without one the library always loads from the server.
+func openLibrary(client *jellyfin.Client) *library.Cache {
+	if client.Token == "" || client.UserID == "" {
+		return nil
+	}
+	cache, _ := library.OpenDefault(client.ServerURL, client.UserID)
+	return cache
+}
+
+// loadCachedArtists shows the cached artists at once and asks the server
+// for what changed since. With nothing cached it loads the first page.
+func (m Model) loadCachedArtists() tea.Cmd {
+	artists, total := m.library.Artists()
+	if len(artists) == 0 {
+		return m.loadArtists(0)
+	}
+	return tea.Sequence(func() tea.Msg {
+		return artistsLoadedMsg{Items: artists, TotalRecordCount: total}
+	}, m.refreshLibrary)
+}
+
+func (m Model) refreshLibrary() tea.Msg {
+	artists, err := m.library.Refresh(m.ctx, m.client)
+	return libraryRefreshedMsg{cache: m.library, artists: artists, err: err}
+}
+
+// libraryRefreshed shows the artists again when the refresh changed them,
+// keeping the cursor where it was.
+func (m Model) libraryRefreshed(msg libraryRefreshedMsg) (Model, tea.Cmd) {
+	if msg.cache != m.library {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+	if !msg.artists {
+		return m, nil
+	}
+	artists, total := m.library.Artists()
+	cursor := m.artistList.Index()
+	m.resetArtists()
+	m.showArtists(artistsLoadedMsg{Items: artists, TotalRecordCount: total})
+	if len(artists) > 0 {
+		m.artistList.Select(min(cursor, len(artists)-1))
+	}
+	return m.moreArtists()
+}
+
+// reloadLibrary forgets the cached library and loads everything shown in
+// the left panel again from the server.
+func (m Model) reloadLibrary() (Model, tea.Cmd) {
+	m.library.Clear()
+	m.resetArtists()
+	m.resetBrowse()
+	m.notice = "Refreshing library…"
+
+	cmds := []tea.Cmd{m.loadArtists(0), m.loadPlaylists}
+	switch m.browse {
+	case browseAlbums, browseGenres, browseDecades, browseComposers:
+		cmds = append(cmds, m.loadBrowse(m.browse, 0))
+	case browseFavorites:
+		cmds = append(cmds, m.loadFavorites())
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// saveLibraryCmd writes the library cache in the background.
+func (m Model) saveLibraryCmd() tea.Cmd {
+	cache := m.library
+	return func() tea.Msg {
+		if err := cache.Save(); err != nil {
+			return errMsg(err)
+		}
+		return nil
+	}
+}
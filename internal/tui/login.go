@@ -0,0 +1,185 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/config"
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loginMethod is how the login screen signs in.
+type loginMethod int
+
+const (
+	loginPassword loginMethod = iota
+	loginQuickConnect
+	loginAPIKey
+	loginMethodCount
+)
+
+// quickConnectPollInterval is how often a pending Quick Connect request is
+// checked for approval.
+const quickConnectPollInterval = 2 * time.Second
+
+func (l loginMethod) String() string {
+	switch l {
+	case loginQuickConnect:
+		return "Quick Connect"
+	case loginAPIKey:
+		return "API key"
+	default:
+		return "Password"
+	}
+}
+
+// labels names the inputs of the login method.
+func (l loginMethod) labels() []string {
+	switch l {
+	case loginQuickConnect:
+		return []string{"Server URL"}
+	case loginAPIKey:
+		return []string{"Server URL", "API key"}
+	default:
+		return []string{"Server URL", "Username", "Password"}
+	}
+}
+
+func (l loginMethod) button() string {
+	if l == loginQuickConnect {
+		return "Get code"
+	}
+	return "Login"
+}
+
+type quickConnectStartedMsg struct {
+	qc *jellyfin.QuickConnect
+}
+
+// quickConnectWaitingMsg means the request is not approved yet.
+type quickConnectWaitingMsg struct {
+	secret string
+}
+
+func newLoginInputs(method loginMethod, serverURL string) []textinput.Model {
+	labels := method.labels()
+	inputs := make([]textinput.Model, len(labels))
+	for i, label := range labels {
+		t := textinput.New()
+		t.CharLimit = 64
+		t.Width = 50
+		t.Placeholder = label
+		t.PromptStyle = inputBlurredStyle
+		t.TextStyle = inputBlurredStyle
+
+		switch label {
+		case "Server URL":
+			t.Focus()
+			t.PromptStyle = inputFocusedStyle
+			t.TextStyle = inputFocusedStyle
+			if serverURL != "" {
+				t.SetValue(serverURL)
+			} else {
+				t.SetValue("https://")
+			}
+		case "Password":
+			t.EchoMode = textinput.EchoPassword
+			t.EchoCharacter = '•'
+		case "API key":
+			t.CharLimit = 128
+			t.EchoMode = textinput.EchoPassword
+			t.EchoCharacter = '•'
+		}
+
+		inputs[i] = t
+	}
+	return inputs
+}
+
+// cycleLoginMethod switches to the next login method, keeping the server
+// URL and dropping any pending Quick Connect request.
+func (m Model) cycleLoginMethod() (Model, tea.Cmd) {
+	serverURL := m.loginInputs[0].Value()
+	m.loginMethod = (m.loginMethod + 1) % loginMethodCount
+	m.loginInputs = newLoginInputs(m.loginMethod, serverURL)
+	m.focusIndex = 0
+	m.quickConnect = nil
+	m.err = nil
+	return m, textinput.Blink
+}
+
+// submitLogin runs the selected login method.
+func (m Model) submitLogin() (Model, tea.Cmd) {
+	m.err = nil
+	m.client.ServerURL = m.loginInputs[0].Value()
+	switch m.loginMethod {
+	case loginQuickConnect:
+		return m, m.startQuickConnect
+	case loginAPIKey:
+		key := m.loginInputs[1].Value()
+		return m, func() tea.Msg {
+			resp, err := m.client.AuthenticateWithAPIKey(m.ctx, key)
+			if err != nil {
+				return err
+			}
+			return m.saveLogin(resp)
+		}
+	default:
+		return m, m.performLogin
+	}
+}
+
+func (m Model) startQuickConnect() tea.Msg {
+	qc, err := m.client.InitiateQuickConnect(m.ctx)
+	if err != nil {
+		return err
+	}
+	return quickConnectStartedMsg{qc: qc}
+}
+
+// pollQuickConnect checks the pending request after a delay and logs in
+// once it has been approved.
+func (m Model) pollQuickConnect(secret string) tea.Cmd {
+	return tea.Tick(quickConnectPollInterval, func(time.Time) tea.Msg {
+		qc, err := m.client.QuickConnectState(m.ctx, secret)
+		if err != nil {
+			return err
+		}
+		if !qc.Authenticated {
+			return quickConnectWaitingMsg{secret: secret}
+		}
+		resp, err := m.client.AuthenticateWithQuickConnect(m.ctx, secret)
+		if err != nil {
+			return err
+		}
+		return m.saveLogin(resp)
+	})
+}
+
+// updateQuickConnect keeps polling while the request shown is pending.
+func (m Model) updateQuickConnect(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case quickConnectStartedMsg:
+		m.quickConnect = msg.qc
+		return m, m.pollQuickConnect(msg.qc.Secret)
+	case quickConnectWaitingMsg:
+		if m.quickConnect == nil || m.quickConnect.Secret != msg.secret {
+			return m, nil
+		}
+		return m, m.pollQuickConnect(msg.secret)
+	}
+	return m, nil
+}
+
+// saveLogin stores the credentials of a successful login.
+func (m Model) saveLogin(resp *jellyfin.AuthResponse) tea.Msg {
+	m.cfg.ServerURL = m.client.ServerURL
+	m.cfg.Username = resp.User.Name
+	m.cfg.Token = resp.AccessToken
+	m.cfg.UserID = resp.User.ID
+	if err := config.SaveConfig(m.cfg); err != nil {
+		return err
+	}
+	return resp
+}
@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -8,7 +10,10 @@ import (
 
 	"github.com/cedev-1/jellyfin-mustui/internal/config"
 	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/cedev-1/jellyfin-mustui/internal/library"
+	"github.com/cedev-1/jellyfin-mustui/internal/offline"
 	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	"github.com/cedev-1/jellyfin-mustui/internal/reporter"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -22,6 +27,7 @@ const (
 	stateLogin sessionState = iota
 	stateLibraryList
 	stateMusicPlayer
+	stateProfilePicker
 )
 
 type panelFocus int
@@ -31,32 +37,143 @@ const (
 	focusTracks
 )
 
+// Player is the playback engine the UI drives: a player.Player in this
+// process, or a ctl.Remote attached to a daemon.
+type Player interface {
+	Init() error
+	Close()
+
+	TogglePause()
+	Next() error
+	Previous() error
+	Seek(pos time.Duration) error
+	SeekRelative(delta time.Duration) error
+	SetVolume(level float64)
+	ToggleMute() bool
+	ToggleShuffle() bool
+	CycleRepeat() player.RepeatMode
+
+	PlayTracks(tracks []player.Track, start int) error
+	PlayFromQueue(index int) error
+	Queue() ([]player.Track, int)
+	Enqueue(tracks ...player.Track)
+	EnqueueNext(tracks ...player.Track)
+	MoveInQueue(from, to int) error
+	RemoveFromQueue(index int) error
+	ClearQueue()
+
+	GetState() player.State
+	GetCurrentTrack() *player.Track
+	GetPosition() time.Duration
+	Buffered() float64
+	Volume() float64
+	Muted() bool
+	Shuffle() bool
+	Repeat() player.RepeatMode
+}
+
 type Model struct {
 	cfg    *config.Config
 	client *jellyfin.Client
-	player *player.Player
-	state  sessionState
-
-	loginInputs []textinput.Model
-	focusIndex  int
-	err         error
+	// ctx is cancelled when the active profile is left or the app quits,
+	// abandoning the requests still running for it.
+	ctx    context.Context
+	cancel context.CancelFunc
+	player Player
+	// local is the same player as player when it runs in this process, and
+	// nil when attached to a daemon, which then keeps the session itself.
+	local    *player.Player
+	reporter *reporter.Reporter
+	state    sessionState
+
+	loginMethod  loginMethod
+	loginInputs  []textinput.Model
+	focusIndex   int
+	quickConnect *jellyfin.QuickConnect
+	err          error
+
+	profileList list.Model
+	// libraryLoaded is set while the library of the active profile is
+	// shown, so the profile picker can go back to it.
+	libraryLoaded bool
+	// pendingProfile is the profile whose token is being read before
+	// switching to it.
+	pendingProfile string
+	onClientChange func(*jellyfin.Client)
 
 	libraryList list.Model
 	artistList  list.Model
 	trackList   list.Model
-
-	artists            []jellyfin.MusicItem
+	queueList   list.Model
+	showQueue   bool
+	// queueLoading is set while refreshQueue fetches the queue, and
+	// queueStale when another fetch was asked for meanwhile.
+	queueLoading bool
+	queueStale   bool
+
+	browse           browseMode
+	browseMenu       list.Model
+	browseMenuActive bool
+	albumList        list.Model
+	genreList        list.Model
+	decadeList       list.Model
+	composerList     list.Model
+	playlistList     list.Model
+	favoriteList     list.Model
+	// favorites maps item IDs to their favorite flag as last seen.
+	favorites       map[string]bool
+	playlists       []jellyfin.MusicItem
+	currentPlaylist *jellyfin.MusicItem
+	offlineList     list.Model
+	// offline is nil when the offline cache could not be opened.
+	offline *offline.Cache
+	// library caches the metadata of the active profile's library.
+	library *library.Cache
+
+	pickerActive  bool
+	pickerList    list.Model
+	pickerItemIDs []string
+	pickerLabel   string
+	promptActive  bool
+	promptInput   textinput.Model
+
+	searchActive bool
+	searchInput  textinput.Model
+	searchList   list.Model
+	// pendingAlbumID is the album to select once the albums of an artist
+	// opened from search have loaded.
+	pendingAlbumID string
+
+	// artists are the artists loaded so far, out of artistTotal.
+	artists        []jellyfin.MusicItem
+	artistTotal    int
+	artistsLoading bool
+	// browsePages track the album, genre and composer lists, which load
+	// a page at a time like the artists.
+	browsePages        [browseModeCount]browsePager
 	currentArtist      *jellyfin.MusicItem
 	albums             []jellyfin.MusicItem
 	selectedAlbumIndex int
 	tracks             []jellyfin.MusicItem
-
-	panelFocus   panelFocus
-	position     time.Duration
-	duration     time.Duration
-	isPlaying    bool
-	isLoading    bool
-	showHelp     bool
+	albumLoad          pendingLoad
+	trackLoad          pendingLoad
+
+	panelFocus panelFocus
+	position   time.Duration
+	duration   time.Duration
+	isPlaying  bool
+	isLoading  bool
+	showHelp   bool
+	notice     string
+	// sessionRestored is set once the previous session has been loaded, so
+	// saving never overwrites it with an empty queue. restoreFailed is set
+	// instead when it could not be loaded; the session is then only saved
+	// once tracks were queued.
+	sessionRestored bool
+	restoreFailed   bool
+	// autosaving is set once the autosave loop runs; it keeps running
+	// across profile switches.
+	autosaving   bool
 	currentTrack *player.Track
 	progressBar  progress.Model
 
@@ -65,81 +182,111 @@ type Model struct {
 }
 
 type tickMsg time.Time
-type artistsLoadedMsg []jellyfin.MusicItem
-type albumsLoadedMsg []jellyfin.MusicItem
-type tracksLoadedMsg []jellyfin.MusicItem
+
+// albumsLoadedMsg and tracksLoadedMsg answer the load numbered seq.
+type albumsLoadedMsg struct {
+	seq    int
+	albums []jellyfin.MusicItem
+}
+type tracksLoadedMsg struct {
+	seq    int
+	tracks []jellyfin.MusicItem
+}
 type trackReadyMsg struct {
-	track      *player.Track
-	queueIndex int
-	err        error
+	track *player.Track
+	err   error
+}
+type seekDoneMsg struct {
+	err error
 }
 type errMsg error
 
-func NewModel(cfg *config.Config, client *jellyfin.Client) Model {
+// loadErrMsg reports an album load (albums set) or track load that failed.
+// It answers the load numbered seq, so it is dropped once another load of
+// the track panel has started.
+type loadErrMsg struct {
+	albums bool
+	seq    int
+	err    error
+}
+
+// pageErrMsg reports a page of the artists (mode browseArtists) or of
+// another browse list that failed to load from start on.
+type pageErrMsg struct {
+	mode  browseMode
+	start int
+	err   error
+}
+
+// NewModel builds the UI around p. For a local player the model sets the
+// player callbacks, so anything else that hooks into them must do so
+// afterwards.
+func NewModel(cfg *config.Config, client *jellyfin.Client, p Player, cache *offline.Cache) Model {
 	m := Model{
 		cfg:        cfg,
 		client:     client,
-		player:     player.New(),
+		player:     p,
+		offline:    cache,
 		state:      stateLogin,
 		panelFocus: focusArtists,
 	}
+	m.local, _ = p.(*player.Player)
+	m.ctx, m.cancel = context.WithCancel(context.Background())
 
 	m.libraryList = list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
-	m.artistList = list.New([]list.Item{}, musicDelegate{}, 0, 0)
+	m.artistList = newBrowseList("Artists")
 	m.trackList = list.New([]list.Item{}, musicDelegate{}, 0, 0)
+	m.queueList = list.New([]list.Item{}, queueDelegate{}, 0, 0)
+	m.queueList.Title = "Queue"
+	m.queueList.Styles.Title = listTitleStyle
 
-	m.artistList.SetShowHelp(false)
 	m.trackList.SetShowHelp(false)
+	m.queueList.SetShowHelp(false)
 	m.libraryList.SetShowHelp(false)
+	m.searchInput = newSearchInput()
+	m.playlistList = newPlaylistList()
+	m.favoriteList = newFavoriteList()
+	m.offlineList = newOfflineList()
+	m.browseMenu = newBrowseMenu()
+	m.albumList = newBrowseList("Albums")
+	m.genreList = newBrowseList("Genres")
+	m.decadeList = newBrowseList("Decades")
+	m.composerList = newBrowseList("Composers")
+	m.favorites = make(map[string]bool)
+	m.pickerList = newPickerList()
+	m.promptInput = newPlaylistNameInput()
+	m.searchList = newSearchList()
+	m.profileList = newProfileList()
 
 	if cfg.Token != "" && cfg.ServerURL != "" && cfg.UserID != "" {
 		m.state = stateMusicPlayer
+		m.libraryLoaded = true
+		m.library = openLibrary(client)
 	} else {
-		m.loginInputs = make([]textinput.Model, 3)
-		var t textinput.Model
-		for i := range m.loginInputs {
-			t = textinput.New()
-			t.CharLimit = 64
-			t.Width = 50
-
-			switch i {
-			case 0:
-				t.Placeholder = "Server URL"
-				t.Focus()
-				t.PromptStyle = inputFocusedStyle
-				t.TextStyle = inputFocusedStyle
-				if cfg.ServerURL != "" {
-					t.SetValue(cfg.ServerURL)
-				} else {
-					t.SetValue("https://")
-				}
-			case 1:
-				t.Placeholder = "Username"
-				t.PromptStyle = inputBlurredStyle
-				t.TextStyle = inputBlurredStyle
-			case 2:
-				t.Placeholder = "Password"
-				t.EchoMode = textinput.EchoPassword
-				t.EchoCharacter = '•'
-				t.PromptStyle = inputBlurredStyle
-				t.TextStyle = inputBlurredStyle
-			}
+		m.loginInputs = newLoginInputs(loginPassword, cfg.ServerURL)
+	}
+
+	m.progressBar = progress.New(progress.WithSolidFill(string(colorSubtext)))
 
-			m.loginInputs[i] = t
+	if m.local != nil {
+		m.reporter = reporter.New(client)
+		m.local.OnProgress = func(pos, dur time.Duration) {
+			m.reporter.Progress(pos, dur)
+		}
+		m.local.OnTrackChange = func(track *player.Track) {
+			m.currentTrack = track
+			m.reporter.TrackChanged(track)
+		}
+		m.local.OnStateChange = func(state player.State) {
+			m.isPlaying = state == player.StatePlaying
+			m.reporter.StateChanged(state)
 		}
-	}
 
-	m.player.OnProgress = func(pos, dur time.Duration) {
-	}
-	m.player.OnTrackChange = func(track *player.Track) {
-		m.currentTrack = track
-	}
-	m.player.OnStateChange = func(state player.State) {
-		m.isPlaying = state == player.StatePlaying
+		if cfg.Volume != nil {
+			m.local.SetVolume(*cfg.Volume)
+		}
 	}
 
-	m.progressBar = progress.New(progress.WithSolidFill(string(colorSubtext)))
-
 	return m
 }
 
@@ -147,10 +294,35 @@ func (m Model) Init() tea.Cmd {
 	if err := m.player.Init(); err != nil {
 		return func() tea.Msg { return errMsg(err) }
 	}
-	if m.state == stateMusicPlayer {
-		return tea.Batch(m.loadArtists, m.tickCmd())
+	switch m.state {
+	case stateMusicPlayer:
+		return tea.Batch(m.loadLibrary(), m.tickCmd())
+	case stateLogin:
+		return tea.Batch(textinput.Blink, m.tickCmd())
+	}
+	return m.tickCmd()
+}
+
+// loadLibrary loads the library of the active profile and, for a local
+// player, its last session.
+func (m Model) loadLibrary() tea.Cmd {
+	if m.local == nil {
+		return tea.Batch(m.loadCachedArtists(), m.loadPlaylists)
+	}
+	return tea.Batch(m.loadCachedArtists(), m.loadPlaylists, m.restoreSession)
+}
+
+// shutdown saves the session, stops playback and flushes the final session
+// report before exit.
+func (m Model) shutdown() {
+	m.cancel()
+	m.saveSession()
+	m.saveVolume()
+	m.library.Save()
+	m.player.Close()
+	if m.reporter != nil {
+		m.reporter.Close()
 	}
-	return textinput.Blink
 }
 
 func (m Model) tickCmd() tea.Cmd {
@@ -159,31 +331,69 @@ func (m Model) tickCmd() tea.Cmd {
 	})
 }
 
-func (m Model) loadArtists() tea.Msg {
-	artists, err := m.client.GetArtists()
-	if err != nil {
-		return errMsg(err)
+// pendingLoad follows the latest load of one kind. Starting another one
+// cancels it, and its answer, should it still arrive, is told apart by its
+// sequence number and dropped.
+type pendingLoad struct {
+	seq    int
+	cancel context.CancelFunc
+}
+
+// start cancels the load in flight and returns the context and sequence
+// number of the next one.
+func (p *pendingLoad) start(parent context.Context) (context.Context, int) {
+	p.stop()
+	ctx, cancel := context.WithCancel(parent)
+	p.cancel = cancel
+	return ctx, p.seq
+}
+
+// stop cancels the load in flight, if any.
+func (p *pendingLoad) stop() {
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
 	}
-	return artistsLoadedMsg(artists)
+	p.seq++
 }
 
-func (m Model) loadAlbums(artistID string) tea.Cmd {
-	return func() tea.Msg {
-		albums, err := m.client.GetAlbums(artistID)
-		if err != nil {
-			return errMsg(err)
+// stopLoads cancels the album and track loads in flight, before the track
+// panel shows something else.
+func (m *Model) stopLoads() {
+	m.albumLoad.stop()
+	m.trackLoad.stop()
+}
+
+func (m *Model) loadAlbums(artistID string) tea.Cmd {
+	client, cache := m.client, m.library
+	return m.loadAlbumsWith(func(ctx context.Context) ([]jellyfin.MusicItem, error) {
+		if albums, ok := cache.Albums(artistID); ok {
+			return albums, nil
 		}
-		return albumsLoadedMsg(albums)
-	}
+		albums, err := client.GetAlbums(ctx, artistID)
+		if err == nil {
+			cache.SetAlbums(artistID, albums)
+		}
+		return albums, err
+	})
 }
 
-func (m Model) loadTracks(albumID string) tea.Cmd {
+// loadTracks fills the track panel with the tracks of an album, replacing
+// any album or track load still running.
+func (m *Model) loadTracks(albumID string) tea.Cmd {
+	m.albumLoad.stop()
+	ctx, seq := m.trackLoad.start(m.ctx)
+	client, cache := m.client, m.library
 	return func() tea.Msg {
-		tracks, err := m.client.GetTracks(albumID)
-		if err != nil {
-			return errMsg(err)
+		tracks, ok := cache.Tracks(albumID)
+		if !ok {
+			var err error
+			if tracks, err = client.GetTracks(ctx, albumID); err != nil {
+				return loadErrMsg{seq: seq, err: err}
+			}
+			cache.SetTracks(albumID, tracks)
 		}
-		return tracksLoadedMsg(tracks)
+		return tracksLoadedMsg{seq: seq, tracks: tracks}
 	}
 }
 
@@ -199,13 +409,80 @@ func (m Model) playTrackAsync(index int) tea.Cmd {
 			err = m.player.PlayFromQueue(index)
 		}
 		if err != nil {
-			return trackReadyMsg{track: nil, queueIndex: -1, err: err}
+			return trackReadyMsg{track: nil, err: err}
 		}
-		return trackReadyMsg{
-			track:      m.player.GetCurrentTrack(),
-			queueIndex: m.player.GetQueueIndex(),
-			err:        nil,
+		return trackReadyMsg{track: m.player.GetCurrentTrack(), err: nil}
+	}
+}
+
+// playTracksAsync replaces the queue with tracks and plays from start.
+func (m Model) playTracksAsync(tracks []player.Track, start int) tea.Cmd {
+	return func() tea.Msg {
+		if err := m.player.PlayTracks(tracks, start); err != nil {
+			return trackReadyMsg{track: nil, err: err}
 		}
+		return trackReadyMsg{track: m.player.GetCurrentTrack(), err: nil}
+	}
+}
+
+// playerTrack converts a library item into a playable track.
+func (m Model) playerTrack(t jellyfin.MusicItem) player.Track {
+	return player.Track{
+		ID:       t.ID,
+		Name:     t.Name,
+		Artist:   t.AlbumArtist,
+		Album:    t.Album,
+		AlbumID:  t.AlbumID,
+		Duration: time.Duration(t.RunTimeTicks/10000000) * time.Second,
+		URL:      m.client.GetAudioStreamURL(t.ID, player.SupportedContainers),
+	}
+}
+
+func (m Model) playerTracks(items []jellyfin.MusicItem) []player.Track {
+	tracks := make([]player.Track, len(items))
+	for i, t := range items {
+		tracks[i] = m.playerTrack(t)
+	}
+	return tracks
+}
+
+// saveVolume writes the volume to the config file if it was changed other
+// than with changeVolume, such as over MPRIS.
+func (m Model) saveVolume() {
+	if m.local == nil {
+		return
+	}
+	volume := m.local.Volume()
+	if m.cfg.Volume != nil && *m.cfg.Volume == volume || m.cfg.Volume == nil && volume == 1 {
+		return
+	}
+	m.cfg.Volume = &volume
+	config.SaveConfig(m.cfg)
+}
+
+// changeVolume adjusts the volume and remembers it in the config file.
+func (m Model) changeVolume(delta float64) tea.Cmd {
+	volume := min(max(m.player.Volume()+delta, 0), 1)
+	m.cfg.Volume = &volume
+	cfg, p := *m.cfg, m.player
+	return func() tea.Msg {
+		p.SetVolume(volume)
+		if err := config.SaveConfig(&cfg); err != nil {
+			return errMsg(err)
+		}
+		return nil
+	}
+}
+
+func (m Model) seekAsync(delta time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		return seekDoneMsg{err: m.player.SeekRelative(delta)}
+	}
+}
+
+func (m Model) seekToAsync(pos time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		return seekDoneMsg{err: m.player.Seek(pos)}
 	}
 }
 
@@ -215,7 +492,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
-			m.player.Close()
+			m.shutdown()
 			return m, tea.Quit
 		}
 	case tea.WindowSizeMsg:
@@ -223,25 +500,36 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		listHeight := m.height - 10
 		m.artistList.SetSize(m.width/3, listHeight)
+		m.playlistList.SetSize(m.width/3, listHeight)
+		m.favoriteList.SetSize(m.width/3, listHeight)
+		for _, l := range []*list.Model{&m.browseMenu, &m.albumList, &m.genreList, &m.decadeList, &m.composerList} {
+			l.SetSize(m.width/3, listHeight)
+		}
+		m.profileList.SetSize(m.width/2, listHeight)
 		m.trackList.SetSize(m.width*2/3, listHeight)
+		m.queueList.SetSize(m.width*2/3, listHeight)
 		m.progressBar.Width = m.width - 30
 		if m.progressBar.Width > 80 {
 			m.progressBar.Width = 80
 		}
 	case tickMsg:
 		var cmds []tea.Cmd
-		if m.player.GetState() == player.StatePlaying {
+		state := m.player.GetState()
+		m.isPlaying = state == player.StatePlaying
+		if track := m.player.GetCurrentTrack(); track != nil {
+			m.currentTrack = track
+			m.duration = track.Duration
+		}
+		if state == player.StatePlaying {
 			m.position = m.player.GetPosition()
-			track := m.player.GetCurrentTrack()
-			if track != nil {
-				m.currentTrack = track
-				m.duration = track.Duration
-			}
 			if m.duration > 0 {
 				percent := float64(m.position) / float64(m.duration)
 				cmds = append(cmds, m.progressBar.SetPercent(percent))
 			}
 		}
+		if m.showQueue && !m.queueLoading {
+			cmds = append(cmds, m.refreshQueue())
+		}
 		cmds = append(cmds, m.tickCmd())
 		return m, tea.Batch(cmds...)
 	case progress.FrameMsg:
@@ -258,13 +546,70 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.track != nil {
 			m.duration = msg.track.Duration
 		}
-		if msg.queueIndex >= 0 {
-			m.trackList.Select(msg.queueIndex)
-		}
 		m.isPlaying = true
 		m.err = nil
+		cmd := m.refreshQueue()
+		return m, cmd
+	case seekDoneMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.position = m.player.GetPosition()
+		if m.duration > 0 {
+			return m, m.progressBar.SetPercent(float64(m.position) / float64(m.duration))
+		}
+		return m, nil
+	case sessionRestoredMsg:
+		m.sessionRestored = msg.err == nil
+		m.restoreFailed = msg.err != nil
+		if msg.err != nil {
+			m.err = msg.err
+		}
+		if !m.autosaving {
+			m.autosaving = true
+			cmds = append(cmds, m.autosaveCmd())
+		}
+		if msg.track != nil {
+			m.currentTrack = msg.track
+			m.duration = msg.track.Duration
+			m.position = msg.position
+			if m.duration > 0 {
+				cmds = append(cmds, m.progressBar.SetPercent(float64(m.position)/float64(m.duration)))
+			}
+		}
+		return m, tea.Batch(cmds...)
+	case autosaveMsg:
+		return m, tea.Batch(m.saveSessionCmd(), m.saveLibraryCmd(), m.autosaveCmd())
+	case ControlMsg:
+		return m.handleControl(msg)
+	case controlDoneMsg:
+		cmd := m.finishControl(msg)
+		return m, cmd
+	case remoteEnqueueMsg:
+		cmd := m.finishRemoteEnqueue(msg)
+		return m, cmd
+	case playerDoneMsg:
+		cmd := m.finishPlayerCmd(msg)
+		return m, cmd
+	case queueLoadedMsg:
+		cmd := m.showQueueItems(msg)
+		return m, cmd
 	case errMsg:
-		m.err = msg
+		// Loads cancelled on purpose are not errors.
+		if !errors.Is(msg, context.Canceled) {
+			m.err = msg
+		}
+	case loadErrMsg:
+		load := m.trackLoad
+		if msg.albums {
+			load = m.albumLoad
+		}
+		if msg.seq == load.seq && !errors.Is(msg.err, context.Canceled) {
+			m.err = msg.err
+		}
+	case pageErrMsg:
+		m.pageFailed(msg)
 	}
 
 	switch m.state {
@@ -274,6 +619,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateLibraryList(msg)
 	case stateMusicPlayer:
 		return m.updateMusicPlayer(msg)
+	case stateProfilePicker:
+		return m.updateProfilePicker(msg)
 	}
 
 	return m, tea.Batch(cmds...)
@@ -287,6 +634,8 @@ func (m Model) View() string {
 		return m.libraryList.View()
 	case stateMusicPlayer:
 		return m.viewMusicPlayer()
+	case stateProfilePicker:
+		return m.viewProfilePicker()
 	}
 	return "Unknown state"
 }
@@ -295,19 +644,35 @@ func (m Model) updateLogin(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case *jellyfin.AuthResponse:
 		m.state = stateMusicPlayer
-		return m, tea.Batch(m.loadArtists, m.tickCmd())
+		m.libraryLoaded = true
+		m.setClient(m.client)
+		return m, m.loadLibrary()
 
 	case error:
 		m.err = msg
+		m.quickConnect = nil
 		return m, nil
 
+	case quickConnectStartedMsg, quickConnectWaitingMsg:
+		return m.updateQuickConnect(msg)
+
 	case tea.KeyMsg:
 		switch msg.String() {
+		case "ctrl+t":
+			return m.cycleLoginMethod()
+		case "esc":
+			// Without a pending Quick Connect request, go back to the
+			// saved profiles.
+			if m.quickConnect == nil && len(m.cfg.Profiles) > 0 {
+				return m.openProfilePicker()
+			}
+			m.quickConnect = nil
+			return m, nil
 		case "tab", "shift+tab", "enter", "up", "down":
 			s := msg.String()
 
 			if s == "enter" && m.focusIndex == len(m.loginInputs) {
-				return m, m.performLogin
+				return m.submitLogin()
 			}
 
 			if s == "up" || s == "shift+tab" {
@@ -353,25 +718,47 @@ func (m *Model) updateInputs(msg tea.Msg) tea.Cmd {
 func (m Model) viewLogin() string {
 	title := titleStyle.Render("JELLYFIN-MUSTUI")
 
-	labels := []string{"Server URL", "Username", "Password"}
+	methods := make([]string, loginMethodCount)
+	for i := range methods {
+		method := loginMethod(i)
+		if method == m.loginMethod {
+			methods[i] = inputFocusedStyle.Render(method.String())
+		} else {
+			methods[i] = inputBlurredStyle.Render(method.String())
+		}
+	}
+
+	labels := m.loginMethod.labels()
 	inputs := make([]string, len(m.loginInputs))
 	for i := range m.loginInputs {
 		inputs[i] = labels[i] + " " + m.loginInputs[i].View()
 	}
 
-	btn := buttonStyle.Render("Login")
+	btn := buttonStyle.Render(m.loginMethod.button())
 	if m.focusIndex == len(m.loginInputs) {
-		btn = activeButtonStyle.Render("Login")
+		btn = activeButtonStyle.Render(m.loginMethod.button())
 	}
 
 	content := lipgloss.JoinVertical(lipgloss.Center,
 		title,
 		"\n",
+		strings.Join(methods, "  ·  "),
+		helpStyle.Render("Ctrl+T to switch"),
+		"\n",
 		strings.Join(inputs, "\n\n"),
 		"\n",
 		btn,
 	)
 
+	if m.quickConnect != nil {
+		content = lipgloss.JoinVertical(lipgloss.Center, content,
+			"\n",
+			"Enter this code in Jellyfin under Quick Connect:",
+			titleStyle.Render(m.quickConnect.Code),
+			helpStyle.Render("Waiting for approval… Esc to cancel"),
+		)
+	}
+
 	if m.err != nil {
 		content = lipgloss.JoinVertical(lipgloss.Center, content, errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
 	}
@@ -386,20 +773,12 @@ func (m Model) performLogin() tea.Msg {
 	pass := m.loginInputs[2].Value()
 
 	m.client.ServerURL = url
-	resp, err := m.client.Authenticate(user, pass)
+	resp, err := m.client.Authenticate(m.ctx, user, pass)
 	if err != nil {
 		return err
 	}
 
-	m.cfg.ServerURL = url
-	m.cfg.Username = user
-	m.cfg.Token = resp.AccessToken
-	m.cfg.UserID = resp.User.ID
-	if err := config.SaveConfig(m.cfg); err != nil {
-		return err
-	}
-
-	return resp
+	return m.saveLogin(resp)
 }
 
 func (m Model) updateLibraryList(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -411,66 +790,110 @@ func (m Model) updateLibraryList(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) updateMusicPlayer(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case artistsLoadedMsg:
-		m.artists = msg
-		items := make([]list.Item, len(msg))
-		for i, a := range msg {
-			items[i] = musicItem{a}
-		}
-		m.artistList = list.New(items, musicDelegate{}, m.width/3, m.height-10)
-		m.artistList.Title = "Artists"
-		m.artistList.Styles.Title = listTitleStyle
-		m.artistList.SetShowHelp(false)
-		m.artistList.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(colorPrimary)
-		m.artistList.FilterInput.Cursor.Style = lipgloss.NewStyle().Foreground(colorPrimary)
+		m.showArtists(msg)
+		// The cursor may already be near the end of a short page.
+		return m.moreArtists()
 
 	case albumsLoadedMsg:
-		m.albums = msg
+		if msg.seq != m.albumLoad.seq {
+			return m, nil
+		}
+		m.currentPlaylist = nil
+		m.albums = msg.albums
 		m.selectedAlbumIndex = 0
-		if len(msg) > 0 {
-			return m, m.loadTracks(msg[0].ID)
+		for i, a := range msg.albums {
+			if a.ID == m.pendingAlbumID {
+				m.selectedAlbumIndex = i
+			}
+		}
+		m.pendingAlbumID = ""
+		if len(msg.albums) > 0 {
+			cmd := m.loadTracks(msg.albums[m.selectedAlbumIndex].ID)
+			return m, cmd
 		}
 
+	case searchResultsMsg:
+		return m.updateSearch(msg)
+
 	case tracksLoadedMsg:
-		m.tracks = msg
+		if msg.seq != m.trackLoad.seq {
+			return m, nil
+		}
+		title := "Tracks"
+		if len(m.albums) > 0 && m.selectedAlbumIndex < len(m.albums) {
+			title = m.albums[m.selectedAlbumIndex].Name
+		}
+		m.setTracks(msg.tracks, title)
 
-		items := make([]list.Item, len(msg))
-		for i, t := range msg {
-			items[i] = trackItem{MusicItem: t, queueIndex: i}
+	case playlistsLoadedMsg:
+		m.setPlaylists(msg)
+
+	case playlistTracksLoadedMsg:
+		if msg.seq != m.trackLoad.seq || m.currentPlaylist == nil || m.currentPlaylist.ID != msg.playlist.ID {
+			return m, nil
+		}
+		cursor := m.trackList.Index()
+		reload := m.trackList.Title == msg.playlist.Name
+		m.setTracks(msg.tracks, msg.playlist.Name)
+		if reload && len(msg.tracks) > 0 {
+			m.trackList.Select(min(cursor, len(msg.tracks)-1))
 		}
 
-		m.trackList = list.New(items, trackDelegate{}, m.width*2/3, m.height-10)
-		if len(m.albums) > 0 && m.selectedAlbumIndex < len(m.albums) {
-			m.trackList.Title = m.albums[m.selectedAlbumIndex].Name
-		} else {
-			m.trackList.Title = "Tracks"
-		}
-		m.trackList.Styles.Title = listTitleStyle
-		m.trackList.SetShowHelp(false)
-		m.trackList.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(colorPrimary)
-		m.trackList.FilterInput.Cursor.Style = lipgloss.NewStyle().Foreground(colorPrimary)
-
-		queue := make([]player.Track, len(msg))
-		for i, t := range msg {
-			queue[i] = player.Track{
-				ID:       t.ID,
-				Name:     t.Name,
-				Artist:   t.AlbumArtist,
-				Album:    t.Album,
-				Duration: time.Duration(t.RunTimeTicks/10000000) * time.Second,
-				URL:      m.client.GetAudioStreamURL(t.ID),
-			}
+	case favoritesLoadedMsg:
+		m.showFavorites(msg)
+
+	case browseLoadedMsg:
+		m.showBrowse(msg)
+		return m.moreBrowse()
+
+	case browseTracksLoadedMsg:
+		if msg.seq != m.trackLoad.seq {
+			return m, nil
 		}
-		m.player.SetQueue(queue)
+		m.showBrowseTracks(msg)
+
+	case libraryRefreshedMsg:
+		return m.libraryRefreshed(msg)
+
+	case favoriteToggledMsg:
+		if msg.err != nil {
+			m.setFavorite(msg.id, !msg.favorite)
+			m.err = msg.err
+		}
+
+	case playlistEditedMsg:
+		return m.playlistEdited(msg)
+
+	case offlineDoneMsg:
+		m.offlineDone(msg)
 
 	case tea.KeyMsg:
-		if m.artistList.SettingFilter() || m.trackList.SettingFilter() {
+		if m.searchActive {
+			return m.updateSearch(msg)
+		}
+		if m.pickerActive {
+			return m.updatePicker(msg)
+		}
+		if m.promptActive {
+			return m.updatePrompt(msg)
+		}
+		if m.browseList().SettingFilter() || m.trackList.SettingFilter() || m.queueList.SettingFilter() {
+			return m.updateFocusedList(msg)
+		}
+		m.notice = ""
+		if m.queueFocused() {
 			var cmd tea.Cmd
-			if m.panelFocus == focusArtists {
-				m.artistList, cmd = m.artistList.Update(msg)
-			} else {
-				m.trackList, cmd = m.trackList.Update(msg)
+			var handled bool
+			if m, cmd, handled = m.updateQueue(msg); handled {
+				return m, cmd
+			}
+		}
+		if m.editingPlaylist() {
+			var cmd tea.Cmd
+			var handled bool
+			if m, cmd, handled = m.updatePlaylist(msg); handled {
+				return m, cmd
 			}
-			return m, cmd
 		}
 		switch msg.String() {
 		case "tab":
@@ -479,42 +902,112 @@ func (m Model) updateMusicPlayer(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				m.panelFocus = focusArtists
 			}
+		case "F":
+			return m.openSearch()
+		case "b":
+			return m.openBrowseMenu()
+		case "]":
+			return m.cycleBrowse(1)
+		case "[":
+			return m.cycleBrowse(-1)
+		case "*":
+			return m.toggleFavorite()
+		case "D":
+			return m.toggleOffline()
+		case "ctrl+p":
+			return m.openProfilePicker()
+		case "ctrl+r":
+			return m.reloadLibrary()
+		case "P":
+			if m.panelFocus == focusTracks && !m.showQueue {
+				if item, ok := m.trackList.SelectedItem().(trackItem); ok {
+					return m.openPlaylistPicker([]jellyfin.MusicItem{item.MusicItem}, item.Name)
+				}
+			}
+			return m, nil
+		case "O":
+			if m.currentPlaylist == nil && len(m.albums) > 0 {
+				return m.openPlaylistPicker(m.tracks, m.albums[m.selectedAlbumIndex].Name)
+			}
+			return m, nil
+		case "N":
+			return m.openNamePrompt(nil)
+		case "e":
+			m.showQueue = !m.showQueue
+			if m.showQueue {
+				cmd := m.refreshQueue()
+				return m, cmd
+			}
+			return m, nil
+		case "a":
+			if m.panelFocus == focusTracks && !m.showQueue {
+				cmd := m.enqueueSelected(false)
+				return m, cmd
+			}
+		case "A":
+			if m.panelFocus == focusTracks && !m.showQueue {
+				cmd := m.enqueueSelected(true)
+				return m, cmd
+			}
 		case "h", "left":
-			if m.panelFocus == focusTracks && len(m.albums) > 0 {
+			if m.panelFocus == focusTracks && !m.showQueue && len(m.albums) > 0 {
 				m.selectedAlbumIndex--
 				if m.selectedAlbumIndex < 0 {
 					m.selectedAlbumIndex = len(m.albums) - 1
 				}
-				return m, m.loadTracks(m.albums[m.selectedAlbumIndex].ID)
+				cmd := m.loadTracks(m.albums[m.selectedAlbumIndex].ID)
+				return m, cmd
 			}
 		case "l", "right":
-			if m.panelFocus == focusTracks && len(m.albums) > 0 {
+			if m.panelFocus == focusTracks && !m.showQueue && len(m.albums) > 0 {
 				m.selectedAlbumIndex++
 				if m.selectedAlbumIndex >= len(m.albums) {
 					m.selectedAlbumIndex = 0
 				}
-				return m, m.loadTracks(m.albums[m.selectedAlbumIndex].ID)
+				cmd := m.loadTracks(m.albums[m.selectedAlbumIndex].ID)
+				return m, cmd
 			}
 		case " ":
-			m.player.TogglePause()
-			m.isPlaying = m.player.GetState() == player.StatePlaying
+			return m, m.playerCmd(func(p Player) error {
+				p.TogglePause()
+				return nil
+			})
 		case "n":
 			m.isLoading = true
 			return m, m.playTrackAsync(-1)
 		case "p":
 			m.isLoading = true
 			return m, m.playTrackAsync(-2)
+		case ",":
+			return m, m.seekAsync(-seekStep)
+		case ".":
+			return m, m.seekAsync(seekStep)
+		case "+", "=":
+			return m, m.changeVolume(volumeStep)
+		case "-":
+			return m, m.changeVolume(-volumeStep)
+		case "m":
+			return m, m.playerCmd(func(p Player) error {
+				p.ToggleMute()
+				return nil
+			})
+		case "s":
+			return m, m.playerCmd(func(p Player) error {
+				p.ToggleShuffle()
+				return nil
+			})
+		case "r":
+			return m, m.playerCmd(func(p Player) error {
+				p.CycleRepeat()
+				return nil
+			})
 		case "enter":
 			if m.panelFocus == focusArtists {
-				if item, ok := m.artistList.SelectedItem().(musicItem); ok {
-					m.currentArtist = &item.MusicItem
-					m.panelFocus = focusTracks
-					return m, m.loadAlbums(item.ID)
-				}
+				return m.openBrowseItem()
 			} else {
 				if item, ok := m.trackList.SelectedItem().(trackItem); ok {
 					m.isLoading = true
-					return m, m.playTrackAsync(item.queueIndex)
+					return m, m.playTracksAsync(m.playerTracks(m.tracks), item.index)
 				}
 			}
 		case "q":
@@ -522,7 +1015,7 @@ func (m Model) updateMusicPlayer(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.showHelp = false
 				return m, nil
 			}
-			m.player.Close()
+			m.shutdown()
 			return m, tea.Quit
 		case "?":
 			m.showHelp = !m.showHelp
@@ -532,13 +1025,62 @@ func (m Model) updateMusicPlayer(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.showHelp = false
 				return m, nil
 			}
+			if m.browseMenuActive {
+				m.browseMenuActive = false
+				return m, nil
+			}
+		}
+	case tea.MouseMsg:
+		if m.searchActive || m.pickerActive || m.promptActive {
+			return m, nil
 		}
+		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+			if fraction, ok := m.progressBarHit(msg.X, msg.Y); ok {
+				return m, m.seekToAsync(time.Duration(fraction * float64(m.duration)))
+			}
+		}
+		return m, nil
+	}
+
+	return m.updateFocusedList(msg)
+}
+
+// setTracks fills the track panel.
+func (m *Model) setTracks(tracks []jellyfin.MusicItem, title string) {
+	m.tracks = tracks
+	m.rememberFavorites(tracks)
+
+	items := make([]list.Item, len(tracks))
+	for i, t := range tracks {
+		items[i] = trackItem{MusicItem: t, index: i}
 	}
 
+	m.trackList = list.New(items, trackDelegate{}, m.width*2/3, m.height-10)
+	m.trackList.Title = title
+	m.trackList.Styles.Title = listTitleStyle
+	m.trackList.SetShowHelp(false)
+	m.trackList.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(colorPrimary)
+	m.trackList.FilterInput.Cursor.Style = lipgloss.NewStyle().Foreground(colorPrimary)
+}
+
+func (m Model) queueFocused() bool {
+	return m.panelFocus == focusTracks && m.showQueue
+}
+
+// updateFocusedList forwards a message to the list in the focused panel.
+func (m Model) updateFocusedList(msg tea.Msg) (Model, tea.Cmd) {
 	var cmd tea.Cmd
-	if m.panelFocus == focusArtists {
-		m.artistList, cmd = m.artistList.Update(msg)
-	} else {
+	switch {
+	case m.panelFocus == focusArtists:
+		browseList := m.browseList()
+		*browseList, cmd = browseList.Update(msg)
+		var artists, more tea.Cmd
+		m, artists = m.moreArtists()
+		m, more = m.moreBrowse()
+		cmd = tea.Batch(cmd, artists, more)
+	case m.showQueue:
+		m.queueList, cmd = m.queueList.Update(msg)
+	default:
 		m.trackList, cmd = m.trackList.Update(msg)
 	}
 	return m, cmd
@@ -562,15 +1104,22 @@ func (m Model) viewMusicPlayer() string {
 	m.artistList.SetSize(artistWidth-2, listHeight)
 	m.artistList.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(colorPrimary)
 	m.artistList.FilterInput.Cursor.Style = lipgloss.NewStyle().Foreground(colorPrimary)
+	m.playlistList.SetSize(artistWidth-2, listHeight)
+	m.favoriteList.SetSize(artistWidth-2, listHeight)
+	m.offlineList.SetSize(artistWidth-2, listHeight)
+	for _, l := range []*list.Model{&m.browseMenu, &m.albumList, &m.genreList, &m.decadeList, &m.composerList} {
+		l.SetSize(artistWidth-2, listHeight)
+	}
 	m.trackList.SetSize(trackWidth-2, listHeight)
 	m.trackList.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(colorPrimary)
 	m.trackList.FilterInput.Cursor.Style = lipgloss.NewStyle().Foreground(colorPrimary)
+	m.queueList.SetSize(trackWidth-2, listHeight)
 
 	artistStyle := panelStyle.Width(artistWidth).Height(panelHeight)
 	if m.panelFocus == focusArtists {
 		artistStyle = activePanelStyle.Width(artistWidth).Height(panelHeight)
 	}
-	artistPanel := artistStyle.Render(m.artistList.View())
+	artistPanel := artistStyle.Render(m.browseList().View())
 
 	albumIndicator := ""
 	if len(m.albums) > 0 {
@@ -581,6 +1130,9 @@ func (m Model) viewMusicPlayer() string {
 	if albumIndicator != "" {
 		trackContent = albumHeaderStyle.Render(albumIndicator) + "\n" + trackContent
 	}
+	if m.showQueue {
+		trackContent = m.queueList.View()
+	}
 
 	trackStyle := panelStyle.Width(trackWidth).Height(panelHeight)
 	if m.panelFocus == focusTracks {
@@ -601,6 +1153,9 @@ func (m Model) viewMusicPlayer() string {
 	}
 
 	hint := helpStyle.Render("Press ? for help")
+	if m.notice != "" {
+		hint = helpStyle.Render(m.notice)
+	}
 	hintCentered := lipgloss.PlaceHorizontal(m.width, lipgloss.Center, hint)
 
 	elements := []string{
@@ -628,6 +1183,29 @@ func (m Model) viewMusicPlayer() string {
 			"[L/→]      Next album",
 			"[N]        Next track",
 			"[P]        Previous track",
+			"[A]        Add track to queue",
+			"[Shift+A]  Play track next",
+			"[E]        Show / hide queue",
+			"[Shift+J/K] Move queue entry",
+			"[X]        Remove queue entry",
+			"[Shift+C]  Clear queue",
+			"[Shift+F]  Search library",
+			"[B]        Choose browse mode",
+			"[[/]]      Previous / next browse mode",
+			"[*]        Toggle favorite",
+			"[Shift+D]  Download / remove offline",
+			"[Shift+P]  Add track to playlist",
+			"[Shift+O]  Add album to playlist",
+			"[Shift+N]  New playlist",
+			"[Ctrl+P]   Switch server profile",
+			"[Ctrl+R]   Refresh library",
+			"[Shift+J/K] Move playlist entry",
+			"[X]        Remove playlist entry",
+			"[,/.]      Seek -/+ 10s",
+			"[+/-]      Volume up / down",
+			"[M]        Mute",
+			"[S]        Toggle shuffle",
+			"[R]        Cycle repeat (off/all/one)",
 			"[Q]        Quit",
 			"[?]        Toggle help",
 			"[Esc]      Close help",
@@ -644,6 +1222,14 @@ func (m Model) viewMusicPlayer() string {
 		modal := modalStyle.Render(helpContent)
 		view = lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, modal)
 	}
+	switch {
+	case m.searchActive:
+		view = m.viewSearch()
+	case m.pickerActive:
+		view = m.viewPicker()
+	case m.promptActive:
+		view = m.viewPrompt()
+	}
 
 	return view
 }
@@ -679,21 +1265,89 @@ func (m Model) renderNowPlaying() string {
 	trackStyle := lipgloss.NewStyle().Bold(true).Foreground(colorText)
 	artistStyle := lipgloss.NewStyle().Foreground(colorSubtext)
 	trackInfo := trackStyle.Render(m.currentTrack.Name) + "  " + artistStyle.Render(m.currentTrack.Artist)
-	posStr := formatDuration(m.position)
-	durStr := formatDuration(m.duration)
-	timeStr := fmt.Sprintf("%s / %s", posStr, durStr)
-
-	content := fmt.Sprintf("%s  %s\n%s  %s", largeIcon, trackInfo, m.progressBar.View(), timeStr)
+	if m.favorites[m.currentTrack.ID] {
+		trackInfo += favoriteStyle.Render(favoriteMarker)
+	}
+	statusInfo := lipgloss.NewStyle().Foreground(colorSecondary).Render(m.modeString() + m.volumeString())
+	content := fmt.Sprintf("%s  %s  %s\n%s  %s", largeIcon, trackInfo, statusInfo, m.progressBar.View(), m.timeString())
 
 	return nowPlayingStyle.Width(m.width - 10).Align(lipgloss.Center).Render(content)
 }
 
+// modeString describes the active shuffle and repeat modes.
+func (m Model) modeString() string {
+	var modes string
+	if m.player.Shuffle() {
+		modes += "⇄ shuffle  "
+	}
+	switch m.player.Repeat() {
+	case player.RepeatAll:
+		modes += "↻ all  "
+	case player.RepeatOne:
+		modes += "↻ one  "
+	}
+	return modes
+}
+
+func (m Model) volumeString() string {
+	if m.player.Muted() {
+		return "🔇 muted"
+	}
+	return fmt.Sprintf("🔊 %d%%", int(m.player.Volume()*100+0.5))
+}
+
+// timeString shows the position and, while the track is still
+// downloading, how much of it is buffered.
+func (m Model) timeString() string {
+	s := fmt.Sprintf("%s / %s", formatDuration(m.position), formatDuration(m.duration))
+	if buffered := m.player.Buffered(); buffered >= 0 && buffered < 1 {
+		s += fmt.Sprintf("  ⇣ %d%%", int(buffered*100))
+	}
+	return s
+}
+
+// progressBarHit reports whether the screen cell (x, y) lies on the progress
+// bar and, if so, how far along the bar it is. The geometry mirrors the
+// layout built by viewMusicPlayer and renderNowPlaying.
+func (m Model) progressBarHit(x, y int) (float64, bool) {
+	if m.isLoading || m.currentTrack == nil || m.duration <= 0 || m.showHelp {
+		return 0, false
+	}
+
+	panelHeight := m.height - 12
+	if panelHeight < 5 {
+		panelHeight = 5
+	}
+	// header (3) + spacer (1) + bordered panels + margin (1) + border (1) + track line (1)
+	row := 3 + 1 + panelHeight + 2 + 1 + 1 + 1
+	if y != row {
+		return 0, false
+	}
+
+	boxWidth := m.width - 10
+	contentWidth := boxWidth - 4
+	lineWidth := m.progressBar.Width + 2 + lipgloss.Width(m.timeString())
+	// centred box + border (1) + padding (2) + centred line
+	left := (m.width-(boxWidth+2))/2 + 1 + 2 + (contentWidth-lineWidth)/2
+	if x < left || x >= left+m.progressBar.Width {
+		return 0, false
+	}
+	return float64(x-left) / float64(m.progressBar.Width), true
+}
+
 func formatDuration(d time.Duration) string {
 	m := int(d.Minutes())
 	s := int(d.Seconds()) % 60
 	return fmt.Sprintf("%d:%02d", m, s)
 }
 
+const (
+	// seekStep is how far the seek keys move within a track.
+	seekStep = 10 * time.Second
+	// volumeStep is how much the volume keys change the volume.
+	volumeStep = 0.05
+)
+
 type musicItem struct {
 	jellyfin.MusicItem
 }
@@ -713,10 +1367,18 @@ func (d musicDelegate) Render(w io.Writer, m list.Model, index int, listItem lis
 		return
 	}
 
+	name := i.Name
+	if i.Type == "MusicAlbum" && i.AlbumArtist != "" {
+		// Albums listed outside their artist say whose they are.
+		name += helpStyle.Render(" · " + i.AlbumArtist)
+	}
+	if i.UserData.IsFavorite {
+		name += favoriteStyle.Render(favoriteMarker)
+	}
 	if index == m.Index() {
-		fmt.Fprint(w, selectedListItemStyle.Render("> "+i.Name))
+		fmt.Fprint(w, selectedListItemStyle.Render("> "+name))
 	} else {
-		fmt.Fprint(w, listItemStyle.Render("  "+i.Name))
+		fmt.Fprint(w, listItemStyle.Render("  "+name))
 	}
 }
 
@@ -730,7 +1392,7 @@ func (a albumHeader) Description() string { return "" }
 
 type trackItem struct {
 	jellyfin.MusicItem
-	queueIndex int
+	index int
 }
 
 func (t trackItem) FilterValue() string { return t.Name }
@@ -747,10 +1409,14 @@ func (d trackDelegate) Render(w io.Writer, m list.Model, index int, listItem lis
 	case albumHeader:
 		fmt.Fprint(w, albumHeaderStyle.Render(item.name))
 	case trackItem:
+		name := item.Name
+		if item.UserData.IsFavorite {
+			name += favoriteStyle.Render(favoriteMarker)
+		}
 		if index == m.Index() {
-			fmt.Fprint(w, selectedListItemStyle.Render("> "+item.Name))
+			fmt.Fprint(w, selectedListItemStyle.Render("> "+name))
 		} else {
-			fmt.Fprint(w, listItemStyle.Render("  - "+item.Name))
+			fmt.Fprint(w, listItemStyle.Render("  - "+name))
 		}
 	}
 }
@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type offlineDoneMsg struct {
+	item jellyfin.MusicItem
+	err  error
+}
+
+func newOfflineList() list.Model {
+	return newBrowseList("Offline")
+}
+
+// showOffline lists the collections in the offline cache. It reads the local
+// index only, so it works without a connection.
+func (m *Model) showOffline() {
+	var items []list.Item
+	if m.offline != nil {
+		for _, it := range m.offline.Collections() {
+			items = append(items, musicItem{it})
+		}
+	}
+	m.offlineList.SetItems(items)
+}
+
+// openOffline shows the downloaded tracks of an offline collection.
+func (m Model) openOffline(item jellyfin.MusicItem) (Model, tea.Cmd) {
+	m.currentPlaylist = nil
+	m.albums = nil
+	m.selectedAlbumIndex = 0
+	m.setTracks(m.offline.Tracks(item.ID), item.Name)
+	m.panelFocus = focusTracks
+	m.showQueue = false
+	return m, nil
+}
+
+// offlineTarget returns the album, artist or playlist that D acts on: the
+// item under the cursor in the left panel, or the album or playlist shown
+// in the track panel.
+func (m *Model) offlineTarget() (jellyfin.MusicItem, bool) {
+	if m.panelFocus == focusArtists {
+		item, ok := m.browseList().SelectedItem().(musicItem)
+		switch item.Type {
+		case "MusicAlbum", "MusicArtist", "Playlist":
+			return item.MusicItem, ok
+		}
+		return jellyfin.MusicItem{}, false
+	}
+	switch {
+	case m.showQueue:
+		return jellyfin.MusicItem{}, false
+	case m.currentPlaylist != nil:
+		return *m.currentPlaylist, true
+	case len(m.albums) > 0:
+		return m.albums[m.selectedAlbumIndex], true
+	}
+	return jellyfin.MusicItem{}, false
+}
+
+// toggleOffline downloads the target for offline use in the background, or
+// removes it from the cache when it is already there.
+func (m Model) toggleOffline() (Model, tea.Cmd) {
+	if m.offline == nil {
+		m.notice = "The offline cache is not available"
+		return m, nil
+	}
+	item, ok := m.offlineTarget()
+	if !ok {
+		return m, nil
+	}
+
+	if m.offline.Has(item.ID) {
+		if err := m.offline.Remove(item.ID); err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.notice = fmt.Sprintf("Removed %s from offline", item.Name)
+		m.showOffline()
+		return m, nil
+	}
+
+	m.notice = fmt.Sprintf("Downloading %s…", item.Name)
+	return m, func() tea.Msg {
+		tracks, err := m.client.GetItemTracks(m.ctx, item.ID)
+		if err == nil {
+			err = m.offline.Add(m.ctx, m.client, item, tracks)
+		}
+		return offlineDoneMsg{item: item, err: err}
+	}
+}
+
+func (m *Model) offlineDone(msg offlineDoneMsg) {
+	if msg.err != nil {
+		m.err = fmt.Errorf("downloading %s: %w", msg.item.Name, msg.err)
+		return
+	}
+	m.notice = fmt.Sprintf("%s is available offline", msg.item.Name)
+	m.showOffline()
+}
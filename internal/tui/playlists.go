@@ -0,0 +1,332 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type playlistsLoadedMsg []jellyfin.MusicItem
+type playlistTracksLoadedMsg struct {
+	seq      int
+	playlist jellyfin.MusicItem
+	tracks   []jellyfin.MusicItem
+}
+
+// playlistEditedMsg reports the outcome of a change to a playlist.
+type playlistEditedMsg struct {
+	playlistID string
+	notice     string
+	err        error
+}
+
+// newPlaylistItem is the first entry of the playlist picker.
+type newPlaylistItem struct{}
+
+func (n newPlaylistItem) FilterValue() string { return "" }
+func (n newPlaylistItem) Title() string       { return "+ New playlist" }
+func (n newPlaylistItem) Description() string { return "" }
+
+type pickerDelegate struct{}
+
+func (d pickerDelegate) Height() int                             { return 1 }
+func (d pickerDelegate) Spacing() int                            { return 0 }
+func (d pickerDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d pickerDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	var name string
+	switch item := listItem.(type) {
+	case newPlaylistItem:
+		name = item.Title()
+	case musicItem:
+		name = item.Name
+	default:
+		return
+	}
+	if index == m.Index() {
+		fmt.Fprint(w, selectedListItemStyle.Render("> "+name))
+	} else {
+		fmt.Fprint(w, listItemStyle.Render("  "+name))
+	}
+}
+
+func newPlaylistList() list.Model {
+	l := list.New([]list.Item{}, musicDelegate{}, 0, 0)
+	l.Title = "Playlists"
+	l.Styles.Title = listTitleStyle
+	l.SetShowHelp(false)
+	l.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(colorPrimary)
+	l.FilterInput.Cursor.Style = lipgloss.NewStyle().Foreground(colorPrimary)
+	return l
+}
+
+func newPickerList() list.Model {
+	l := list.New([]list.Item{}, pickerDelegate{}, 0, 0)
+	l.SetShowHelp(false)
+	l.SetShowTitle(false)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	return l
+}
+
+func newPlaylistNameInput() textinput.Model {
+	t := textinput.New()
+	t.Placeholder = "Playlist name"
+	t.CharLimit = 100
+	t.Width = 40
+	t.PromptStyle = inputFocusedStyle
+	t.TextStyle = inputFocusedStyle
+	return t
+}
+
+func (m Model) loadPlaylists() tea.Msg {
+	playlists, err := m.client.GetPlaylists(m.ctx)
+	if err != nil {
+		return errMsg(err)
+	}
+	return playlistsLoadedMsg(playlists)
+}
+
+// loadPlaylistTracks fills the track panel with the tracks of playlist,
+// replacing any album or track load still running.
+func (m *Model) loadPlaylistTracks(playlist jellyfin.MusicItem) tea.Cmd {
+	m.albumLoad.stop()
+	ctx, seq := m.trackLoad.start(m.ctx)
+	client := m.client
+	return func() tea.Msg {
+		tracks, err := client.GetPlaylistItems(ctx, playlist.ID)
+		if err != nil {
+			return loadErrMsg{seq: seq, err: err}
+		}
+		return playlistTracksLoadedMsg{seq: seq, playlist: playlist, tracks: tracks}
+	}
+}
+
+func (m *Model) setPlaylists(playlists []jellyfin.MusicItem) {
+	m.playlists = playlists
+	items := make([]list.Item, len(playlists))
+	for i, p := range playlists {
+		items[i] = musicItem{p}
+	}
+	m.playlistList.SetItems(items)
+}
+
+// openPlaylist shows a playlist in the track panel.
+func (m Model) openPlaylist(playlist jellyfin.MusicItem) (Model, tea.Cmd) {
+	m.stopLoads()
+	m.currentPlaylist = &playlist
+	m.albums = nil
+	m.selectedAlbumIndex = 0
+	m.panelFocus = focusTracks
+	m.showQueue = false
+	cmd := m.loadPlaylistTracks(playlist)
+	return m, cmd
+}
+
+// editingPlaylist reports whether the track panel shows a playlist that the
+// edit keys apply to.
+func (m Model) editingPlaylist() bool {
+	return m.currentPlaylist != nil && m.panelFocus == focusTracks && !m.showQueue
+}
+
+// updatePlaylist handles the keys that edit the open playlist. Changes are
+// applied locally first and sent to the server in the background.
+func (m Model) updatePlaylist(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
+	item, ok := m.trackList.SelectedItem().(trackItem)
+	if !ok || item.PlaylistItemID == "" {
+		return m, nil, false
+	}
+	playlistID := m.currentPlaylist.ID
+
+	switch msg.String() {
+	case "K", "shift+up":
+		if item.index == 0 {
+			return m, nil, true
+		}
+		m.moveTrack(item.index, item.index-1)
+		return m, m.editPlaylistCmd(playlistID, func() error {
+			return m.client.MovePlaylistItem(m.ctx, playlistID, item.PlaylistItemID, item.index-1)
+		}), true
+	case "J", "shift+down":
+		if item.index >= len(m.tracks)-1 {
+			return m, nil, true
+		}
+		m.moveTrack(item.index, item.index+1)
+		return m, m.editPlaylistCmd(playlistID, func() error {
+			return m.client.MovePlaylistItem(m.ctx, playlistID, item.PlaylistItemID, item.index+1)
+		}), true
+	case "x", "delete":
+		tracks := append([]jellyfin.MusicItem(nil), m.tracks[:item.index]...)
+		tracks = append(tracks, m.tracks[item.index+1:]...)
+		m.setTracks(tracks, m.trackList.Title)
+		if len(tracks) > 0 {
+			m.trackList.Select(min(item.index, len(tracks)-1))
+		}
+		return m, m.editPlaylistCmd(playlistID, func() error {
+			return m.client.RemoveFromPlaylist(m.ctx, playlistID, []string{item.PlaylistItemID})
+		}), true
+	}
+	return m, nil, false
+}
+
+func (m *Model) moveTrack(from, to int) {
+	tracks := append([]jellyfin.MusicItem(nil), m.tracks...)
+	tracks[from], tracks[to] = tracks[to], tracks[from]
+	m.setTracks(tracks, m.trackList.Title)
+	m.trackList.Select(to)
+}
+
+func (m Model) editPlaylistCmd(playlistID string, edit func() error) tea.Cmd {
+	return func() tea.Msg {
+		return playlistEditedMsg{playlistID: playlistID, err: edit()}
+	}
+}
+
+// openPlaylistPicker asks which playlist to add items to.
+func (m Model) openPlaylistPicker(items []jellyfin.MusicItem, label string) (Model, tea.Cmd) {
+	if len(items) == 0 {
+		return m, nil
+	}
+	m.pickerItemIDs = make([]string, len(items))
+	for i, it := range items {
+		m.pickerItemIDs[i] = it.ID
+	}
+	m.pickerLabel = label
+
+	entries := []list.Item{newPlaylistItem{}}
+	for _, p := range m.playlists {
+		entries = append(entries, musicItem{p})
+	}
+	m.pickerList.SetItems(entries)
+	m.pickerList.Select(0)
+	m.pickerActive = true
+	return m, nil
+}
+
+// openNamePrompt asks for the name of a new playlist holding itemIDs.
+func (m Model) openNamePrompt(itemIDs []string) (Model, tea.Cmd) {
+	m.pickerActive = false
+	m.promptActive = true
+	m.pickerItemIDs = itemIDs
+	m.promptInput.SetValue("")
+	return m, m.promptInput.Focus()
+}
+
+func (m Model) updatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.pickerActive = false
+		return m, nil
+	case "enter":
+		switch item := m.pickerList.SelectedItem().(type) {
+		case newPlaylistItem:
+			return m.openNamePrompt(m.pickerItemIDs)
+		case musicItem:
+			m.pickerActive = false
+			ids, label := m.pickerItemIDs, m.pickerLabel
+			return m, func() tea.Msg {
+				err := m.client.AddToPlaylist(m.ctx, item.ID, ids)
+				return playlistEditedMsg{
+					playlistID: item.ID,
+					notice:     fmt.Sprintf("Added %s to %s", label, item.Name),
+					err:        err,
+				}
+			}
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.pickerList, cmd = m.pickerList.Update(msg)
+	return m, cmd
+}
+
+func (m Model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.promptActive = false
+		m.promptInput.Blur()
+		return m, nil
+	case "enter":
+		name := strings.TrimSpace(m.promptInput.Value())
+		if name == "" {
+			return m, nil
+		}
+		m.promptActive = false
+		m.promptInput.Blur()
+		ids := m.pickerItemIDs
+		return m, func() tea.Msg {
+			id, err := m.client.CreatePlaylist(m.ctx, name, ids)
+			return playlistEditedMsg{
+				playlistID: id,
+				notice:     fmt.Sprintf("Created playlist %s", name),
+				err:        err,
+			}
+		}
+	}
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(msg)
+	return m, cmd
+}
+
+// playlistEdited refreshes the playlists after a change reached the server,
+// or reloads the open playlist when it was rejected.
+func (m Model) playlistEdited(msg playlistEditedMsg) (Model, tea.Cmd) {
+	cmds := []tea.Cmd{m.loadPlaylists}
+	if msg.err != nil {
+		m.err = msg.err
+	} else if msg.notice != "" {
+		m.notice = msg.notice
+	}
+	if m.currentPlaylist != nil && m.currentPlaylist.ID == msg.playlistID && (msg.err != nil || msg.notice != "") {
+		cmds = append(cmds, m.loadPlaylistTracks(*m.currentPlaylist))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m Model) viewPicker() string {
+	height := len(m.pickerList.Items())
+	if limit := m.height - 14; height > limit {
+		height = limit
+	}
+	if height < 3 {
+		height = 3
+	}
+	m.pickerList.SetSize(40, height)
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Add to playlist"),
+		helpStyle.Render(m.pickerLabel),
+		"",
+		m.pickerList.View(),
+		"",
+		helpStyle.Render("[Enter] add  [Esc] cancel"),
+	)
+	return m.placeModal(content, 50)
+}
+
+func (m Model) viewPrompt() string {
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("New playlist"),
+		"",
+		m.promptInput.View(),
+		"",
+		helpStyle.Render("[Enter] create  [Esc] cancel"),
+	)
+	return m.placeModal(content, 50)
+}
+
+// placeModal draws content in a bordered box in the middle of the screen.
+func (m Model) placeModal(content string, width int) string {
+	modal := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorPrimary).
+		Padding(1, 2).
+		Width(width).
+		Render(content)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, modal)
+}
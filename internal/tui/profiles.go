@@ -0,0 +1,305 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/config"
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type profileItem struct {
+	config.Profile
+	// add marks the entry that logs in to a new server.
+	add bool
+}
+
+func (i profileItem) Title() string {
+	if i.add {
+		return "+ New profile"
+	}
+	return i.Name
+}
+
+func (i profileItem) Description() string {
+	if i.add {
+		return "Log in to another server"
+	}
+	if i.Username != "" {
+		return i.Username + " @ " + i.ServerURL
+	}
+	return i.ServerURL
+}
+
+func (i profileItem) FilterValue() string { return i.Name }
+
+// profileTokenMsg carries the stored token of a profile being switched to.
+type profileTokenMsg struct {
+	name  string
+	token string
+	err   error
+}
+
+func newProfileList() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Profiles"
+	l.Styles.Title = listTitleStyle
+	l.SetShowHelp(false)
+	l.FilterInput.PromptStyle = lipgloss.NewStyle().Foreground(colorPrimary)
+	l.FilterInput.Cursor.Style = lipgloss.NewStyle().Foreground(colorPrimary)
+	return l
+}
+
+// PickProfile makes the model start on the profile picker instead of the
+// library of the active profile.
+func (m Model) PickProfile() Model {
+	m.fillProfiles()
+	m.state = stateProfilePicker
+	m.libraryLoaded = false
+	return m
+}
+
+// OnClientChange registers f to be called with the new client whenever the
+// active profile changes.
+func (m *Model) OnClientChange(f func(*jellyfin.Client)) {
+	m.onClientChange = f
+}
+
+func (m *Model) fillProfiles() {
+	items := make([]list.Item, 0, len(m.cfg.Profiles)+1)
+	selected := 0
+	for i, p := range m.cfg.Profiles {
+		if p.Name == m.cfg.Profile {
+			selected = i
+		}
+		items = append(items, profileItem{Profile: p})
+	}
+	items = append(items, profileItem{add: true})
+	m.profileList.SetItems(items)
+	m.profileList.Select(selected)
+}
+
+// openProfilePicker lets the user switch to another server. A daemon keeps
+// its own login, so an attached UI cannot switch.
+func (m Model) openProfilePicker() (Model, tea.Cmd) {
+	if m.local == nil {
+		m.notice = "Attached to a daemon; restart it with --profile to switch servers"
+		return m, nil
+	}
+	m.fillProfiles()
+	m.state = stateProfilePicker
+	return m, nil
+}
+
+func (m Model) updateProfilePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(profileTokenMsg); ok {
+		if msg.name != m.pendingProfile {
+			return m, nil
+		}
+		m.pendingProfile = ""
+		if msg.err != nil {
+			m.err = fmt.Errorf("reading the token of %s: %w", msg.name, msg.err)
+			return m, nil
+		}
+		return m.switchProfile(msg.name, msg.token)
+	}
+
+	if key, ok := msg.(tea.KeyMsg); ok && !m.profileList.SettingFilter() {
+		switch key.String() {
+		case "esc", "q":
+			m.pendingProfile = ""
+			// Going back only makes sense once a library is loaded.
+			if m.libraryLoaded {
+				m.state = stateMusicPlayer
+			}
+			return m, nil
+		case "enter":
+			item, ok := m.profileList.SelectedItem().(profileItem)
+			if !ok {
+				return m, nil
+			}
+			if item.add {
+				return m.addProfile()
+			}
+			if item.Name == m.cfg.Profile && m.libraryLoaded {
+				m.state = stateMusicPlayer
+				return m, nil
+			}
+			if item.Token != "" {
+				return m.switchProfile(item.Name, item.Token)
+			}
+			return m, m.loadProfileToken(item.Name)
+		case "x", "delete":
+			item, ok := m.profileList.SelectedItem().(profileItem)
+			if !ok || item.add {
+				return m, nil
+			}
+			return m.removeProfile(item.Name)
+		}
+	}
+
+	var cmd tea.Cmd
+	m.profileList, cmd = m.profileList.Update(msg)
+	return m, cmd
+}
+
+// loadProfileToken reads the stored token of a profile in the background,
+// as the keyring may wait for the user to unlock it.
+func (m *Model) loadProfileToken(name string) tea.Cmd {
+	m.pendingProfile = name
+	m.err = nil
+	return func() tea.Msg {
+		token, err := config.LoadToken(name)
+		return profileTokenMsg{name: name, token: token, err: err}
+	}
+}
+
+// switchProfile makes another profile active and loads its library and
+// last session. Without a token, it asks for a new login to its server.
+func (m Model) switchProfile(name, token string) (Model, tea.Cmd) {
+	m.leaveProfile()
+	if err := m.cfg.UseToken(name, token); err != nil {
+		m.err = err
+		return m, nil
+	}
+	if err := config.SaveConfig(m.cfg); err != nil {
+		m.err = err
+	}
+	if token == "" {
+		m.setClient(m.newClient(m.cfg.ServerURL, "", ""))
+		return m.showLogin(m.cfg.ServerURL)
+	}
+	m.setClient(m.newClient(m.cfg.ServerURL, m.cfg.Token, m.cfg.UserID))
+	m.state = stateMusicPlayer
+	m.libraryLoaded = true
+	return m, m.loadLibrary()
+}
+
+// removeProfile forgets a profile and deletes its token. Removing the
+// active profile stops playback; with no profile left, the login screen is
+// shown.
+func (m Model) removeProfile(name string) (Model, tea.Cmd) {
+	active := name == m.cfg.Profile
+	if active {
+		m.leaveProfile()
+	}
+	if err := m.cfg.RemoveProfile(name); err != nil {
+		m.err = err
+	}
+	if active {
+		m.setClient(m.newClient(m.cfg.ServerURL, "", ""))
+	}
+	if len(m.cfg.Profiles) == 0 {
+		return m.addProfile()
+	}
+	m.fillProfiles()
+	return m, nil
+}
+
+// addProfile shows the login screen for a new server.
+func (m Model) addProfile() (Model, tea.Cmd) {
+	m.leaveProfile()
+	serverURL := m.cfg.ServerURL
+	m.cfg.NewProfile()
+	m.setClient(m.newClient(serverURL, "", ""))
+	return m.showLogin(serverURL)
+}
+
+// showLogin shows the login screen for serverURL.
+func (m Model) showLogin(serverURL string) (Model, tea.Cmd) {
+	m.loginMethod = loginPassword
+	m.loginInputs = newLoginInputs(loginPassword, serverURL)
+	m.focusIndex = 0
+	m.quickConnect = nil
+	m.state = stateLogin
+	return m, textinput.Blink
+}
+
+// leaveProfile saves the session of the active profile, stops playback and
+// forgets everything loaded from its server.
+func (m *Model) leaveProfile() {
+	if m.libraryLoaded {
+		m.saveSession()
+	}
+	if m.local != nil {
+		m.local.Reset()
+	}
+	m.sessionRestored = false
+	m.restoreFailed = false
+	m.libraryLoaded = false
+	m.cancel()
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	m.currentArtist = nil
+	m.albums = nil
+	m.selectedAlbumIndex = 0
+	m.playlists = nil
+	m.currentPlaylist = nil
+	m.pendingAlbumID = ""
+	m.favorites = make(map[string]bool)
+	m.resetArtists()
+	m.playlistList.SetItems(nil)
+	m.favoriteList.SetItems(nil)
+	m.resetBrowse()
+	m.setTracks(nil, "Tracks")
+	m.queueList.SetItems(nil)
+
+	m.browse = browseArtists
+	m.browseMenuActive = false
+	m.panelFocus = focusArtists
+	m.showQueue = false
+	m.currentTrack = nil
+	m.position = 0
+	m.duration = 0
+	m.isPlaying = false
+	m.notice = ""
+	m.err = nil
+}
+
+// newClient returns a client for another login, set up like the current
+// one.
+func (m Model) newClient(serverURL, token, userID string) *jellyfin.Client {
+	client := jellyfin.NewClient(serverURL, token, userID, m.client.Device)
+	client.Timeout = m.client.Timeout
+	client.Retries = m.client.Retries
+	return client
+}
+
+// setClient points the model, the playback reports and any other listener
+// at a new server.
+func (m *Model) setClient(client *jellyfin.Client) {
+	m.client = client
+	m.library.Save()
+	m.library = openLibrary(client)
+	if m.offline != nil {
+		if cache, err := m.offline.ForUser(client.ServerURL, client.UserID); err == nil {
+			m.offline = cache
+		}
+	}
+	if m.reporter != nil {
+		m.reporter.SetClient(client)
+	}
+	if m.onClientChange != nil {
+		m.onClientChange(client)
+	}
+}
+
+func (m Model) viewProfilePicker() string {
+	hint := "Enter to choose · x to remove"
+	if m.libraryLoaded {
+		hint += " · Esc to go back"
+	}
+	if m.pendingProfile != "" {
+		hint = fmt.Sprintf("Reading the token of %s… · Esc to cancel", m.pendingProfile)
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left, m.profileList.View(), helpStyle.Render(hint))
+	if m.err != nil {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	}
+	box := loginBoxStyle.Render(content)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}
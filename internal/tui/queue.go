@@ -0,0 +1,204 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type queueItem struct {
+	track    player.Track
+	index    int
+	current  bool
+	favorite bool
+}
+
+// queueLoadedMsg carries the queue fetched by refreshQueue.
+type queueLoadedMsg struct {
+	tracks  []player.Track
+	current int
+}
+
+// playerDoneMsg reports that a command run by playerCmd has finished.
+type playerDoneMsg struct {
+	err error
+}
+
+func (q queueItem) FilterValue() string { return q.track.Name }
+func (q queueItem) Title() string       { return q.track.Name }
+func (q queueItem) Description() string { return q.track.Artist }
+
+type queueDelegate struct{}
+
+func (d queueDelegate) Height() int                             { return 1 }
+func (d queueDelegate) Spacing() int                            { return 0 }
+func (d queueDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d queueDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	item, ok := listItem.(queueItem)
+	if !ok {
+		return
+	}
+
+	marker := fmt.Sprintf("%2d.", item.index+1)
+	if item.current {
+		marker = " ▶ "
+	}
+	name := item.track.Name
+	if item.favorite {
+		name += favoriteStyle.Render(favoriteMarker)
+	}
+	line := fmt.Sprintf("%s %s  %s", marker, name, helpStyle.Render(item.track.Artist))
+
+	if index == m.Index() {
+		fmt.Fprint(w, selectedListItemStyle.Render(line))
+	} else {
+		fmt.Fprint(w, listItemStyle.Render(line))
+	}
+}
+
+// refreshQueue fetches the queue from the player in the background, as a
+// player attached over the control socket may be slow to answer. Only one
+// fetch runs at a time; one asked for meanwhile follows when it is done.
+func (m *Model) refreshQueue() tea.Cmd {
+	if m.queueLoading {
+		m.queueStale = true
+		return nil
+	}
+	m.queueLoading = true
+	p := m.player
+	return func() tea.Msg {
+		tracks, current := p.Queue()
+		return queueLoadedMsg{tracks: tracks, current: current}
+	}
+}
+
+// showQueueItems fills the queue panel, keeping the cursor.
+func (m *Model) showQueueItems(msg queueLoadedMsg) tea.Cmd {
+	m.queueLoading = false
+	items := make([]list.Item, len(msg.tracks))
+	for i, t := range msg.tracks {
+		items[i] = queueItem{track: t, index: i, current: i == msg.current, favorite: m.favorites[t.ID]}
+	}
+	m.queueList.SetItems(items)
+	m.queueList.Title = fmt.Sprintf("Queue (%d)", len(msg.tracks))
+	if m.queueStale {
+		m.queueStale = false
+		return m.refreshQueue()
+	}
+	return nil
+}
+
+// markQueueFavorites updates the favorite markers of the queue panel.
+func (m *Model) markQueueFavorites() {
+	for i, listItem := range m.queueList.Items() {
+		if item, ok := listItem.(queueItem); ok && item.favorite != m.favorites[item.track.ID] {
+			item.favorite = !item.favorite
+			m.queueList.SetItem(i, item)
+		}
+	}
+}
+
+// playerCmd runs f against the player in the background, as a player
+// attached over the control socket may be slow to answer.
+func (m Model) playerCmd(f func(Player) error) tea.Cmd {
+	p := m.player
+	return func() tea.Msg {
+		return playerDoneMsg{err: f(p)}
+	}
+}
+
+// finishPlayerCmd shows the effect of a command run by playerCmd.
+func (m *Model) finishPlayerCmd(msg playerDoneMsg) tea.Cmd {
+	if msg.err != nil {
+		m.err = msg.err
+	}
+	m.syncPlayer()
+	return m.refreshQueue()
+}
+
+// syncPlayer takes over the playing state and track from the player.
+func (m *Model) syncPlayer() {
+	m.isPlaying = m.player.GetState() == player.StatePlaying
+	if track := m.player.GetCurrentTrack(); track != nil {
+		m.currentTrack = track
+		m.duration = track.Duration
+	}
+}
+
+// updateQueue handles the keys that edit the queue while its panel has focus.
+func (m Model) updateQueue(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
+	item, ok := m.queueList.SelectedItem().(queueItem)
+	if !ok {
+		if msg.String() == "C" {
+			return m, m.playerCmd(func(p Player) error {
+				p.ClearQueue()
+				return nil
+			}), true
+		}
+		return m, nil, false
+	}
+
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "enter":
+		m.isLoading = true
+		return m, m.playTrackAsync(item.index), true
+	case "K", "shift+up":
+		if item.index > 0 {
+			cmd = m.playerCmd(func(p Player) error {
+				return p.MoveInQueue(item.index, item.index-1)
+			})
+			m.queueList.CursorUp()
+		}
+	case "J", "shift+down":
+		if item.index < len(m.queueList.Items())-1 {
+			cmd = m.playerCmd(func(p Player) error {
+				return p.MoveInQueue(item.index, item.index+1)
+			})
+			m.queueList.CursorDown()
+		}
+	case "x", "delete":
+		cmd = m.playerCmd(func(p Player) error {
+			return p.RemoveFromQueue(item.index)
+		})
+	case "C":
+		cmd = m.playerCmd(func(p Player) error {
+			p.ClearQueue()
+			return nil
+		})
+	default:
+		return m, nil, false
+	}
+	return m, cmd, true
+}
+
+// enqueueSelected adds the track under the cursor to the queue, either at the
+// end or right after the playing track.
+func (m *Model) enqueueSelected(next bool) tea.Cmd {
+	item, ok := m.trackList.SelectedItem().(trackItem)
+	if !ok {
+		return nil
+	}
+	return m.enqueue(m.playerTrack(item.MusicItem), next)
+}
+
+// enqueue adds track to the queue, either at the end or right after the
+// playing track.
+func (m *Model) enqueue(track player.Track, next bool) tea.Cmd {
+	if next {
+		m.notice = fmt.Sprintf("Playing next: %s", track.Name)
+	} else {
+		m.notice = fmt.Sprintf("Added to queue: %s", track.Name)
+	}
+	return m.playerCmd(func(p Player) error {
+		if next {
+			p.EnqueueNext(track)
+		} else {
+			p.Enqueue(track)
+		}
+		return nil
+	})
+}
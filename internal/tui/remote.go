@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/ctl"
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// controlTimeout is how long a control request waits for the UI to answer.
+const controlTimeout = 10 * time.Second
+
+// ControlMsg carries a request from the control socket into the program.
+// The answer is sent on Reply, which must be buffered.
+type ControlMsg struct {
+	Request ctl.Request
+	Reply   chan<- ctl.Response
+}
+
+// controlDoneMsg reports that a control request run in the background has
+// been answered.
+type controlDoneMsg struct {
+	err error
+}
+
+type remoteEnqueueMsg struct {
+	tracks []jellyfin.MusicItem
+	reply  chan<- ctl.Response
+	err    error
+}
+
+// ControlHandler answers control requests by passing them to the running
+// program.
+func ControlHandler(p *tea.Program) ctl.Handler {
+	return func(req ctl.Request) ctl.Response {
+		reply := make(chan ctl.Response, 1)
+		p.Send(ControlMsg{Request: req, Reply: reply})
+		select {
+		case resp := <-reply:
+			return resp
+		case <-time.After(controlTimeout):
+			return ctl.Errorf("timed out")
+		}
+	}
+}
+
+// ErrorHandler shows errors met outside the program, such as commands from
+// other clients that failed, in the interface.
+func ErrorHandler(p *tea.Program) func(error) {
+	return func(err error) {
+		p.Send(errMsg(err))
+	}
+}
+
+func (m Model) handleControl(msg ControlMsg) (Model, tea.Cmd) {
+	if m.state != stateMusicPlayer || m.local == nil {
+		msg.Reply <- ctl.Errorf("not logged in")
+		return m, nil
+	}
+
+	req := msg.Request
+	ok := ctl.Response{OK: true}
+	switch req.Command {
+	case ctl.CmdPlayPause:
+		m.player.TogglePause()
+		m.isPlaying = m.player.GetState() == player.StatePlaying
+	case ctl.CmdNext:
+		msg.Reply <- ok
+		m.isLoading = true
+		return m, m.playTrackAsync(-1)
+	case ctl.CmdPrevious:
+		msg.Reply <- ok
+		m.isLoading = true
+		return m, m.playTrackAsync(-2)
+	case ctl.CmdSeek:
+		d, relative, err := ctl.ParseSeek(req.Arg)
+		if err != nil {
+			msg.Reply <- ctl.Errorf("%v", err)
+			return m, nil
+		}
+		msg.Reply <- ok
+		if relative {
+			return m, m.seekAsync(d)
+		}
+		return m, m.seekToAsync(d)
+	case ctl.CmdVolume:
+		level, relative, err := ctl.ParseVolume(req.Arg)
+		if err != nil {
+			msg.Reply <- ctl.Errorf("%v", err)
+			return m, nil
+		}
+		if !relative {
+			level -= m.player.Volume()
+		}
+		msg.Reply <- ok
+		return m, m.changeVolume(level)
+	case ctl.CmdEnqueue:
+		if req.Arg == "" {
+			msg.Reply <- ctl.Errorf("enqueue needs an item ID")
+			return m, nil
+		}
+		return m, m.remoteEnqueue(req.Arg, msg.Reply)
+	case ctl.CmdPlay, ctl.CmdPlayTracks, ctl.CmdPlayIndex:
+		// These may open a stream, which must not hold up the interface.
+		req.Tracks = m.withURLs(req.Tracks)
+		m.isLoading = true
+		return m, m.executeAsync(req, msg.Reply)
+	default:
+		req.Tracks = m.withURLs(req.Tracks)
+		msg.Reply <- ctl.Execute(m.local, req)
+		cmd := m.refreshQueue()
+		return m, cmd
+	}
+	msg.Reply <- ok
+	return m, nil
+}
+
+// executeAsync runs a control request in the background and answers it
+// once the player is done.
+func (m Model) executeAsync(req ctl.Request, reply chan<- ctl.Response) tea.Cmd {
+	local := m.local
+	return func() tea.Msg {
+		resp := ctl.Execute(local, req)
+		reply <- resp
+		if !resp.OK {
+			return controlDoneMsg{err: errors.New(resp.Error)}
+		}
+		return controlDoneMsg{}
+	}
+}
+
+// remoteEnqueue looks up the tracks of an item for the enqueue command.
+func (m Model) remoteEnqueue(itemID string, reply chan<- ctl.Response) tea.Cmd {
+	return func() tea.Msg {
+		tracks, err := m.client.GetItemTracks(m.ctx, itemID)
+		return remoteEnqueueMsg{tracks: tracks, reply: reply, err: err}
+	}
+}
+
+// withURLs fills in the stream URLs of tracks received over the socket.
+func (m Model) withURLs(tracks []player.Track) []player.Track {
+	for i := range tracks {
+		tracks[i].URL = m.client.GetAudioStreamURL(tracks[i].ID, player.SupportedContainers)
+	}
+	return tracks
+}
+
+func (m *Model) finishControl(msg controlDoneMsg) tea.Cmd {
+	m.isLoading = false
+	if msg.err != nil {
+		m.err = msg.err
+	}
+	m.syncPlayer()
+	return m.refreshQueue()
+}
+
+func (m *Model) finishRemoteEnqueue(msg remoteEnqueueMsg) tea.Cmd {
+	if msg.err != nil {
+		msg.reply <- ctl.Errorf("%v", msg.err)
+		return nil
+	}
+	m.player.Enqueue(m.playerTracks(msg.tracks)...)
+	m.notice = fmt.Sprintf("Added %d tracks to queue", len(msg.tracks))
+	msg.reply <- ctl.Response{OK: true}
+	return m.refreshQueue()
+}
@@ -0,0 +1,282 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/jellyfin"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// searchResultsMsg answers the search for term. A failed search sets err.
+type searchResultsMsg struct {
+	term    string
+	results *jellyfin.SearchResults
+	err     error
+}
+
+type searchHeader struct {
+	title string
+}
+
+func (h searchHeader) FilterValue() string { return "" }
+func (h searchHeader) Title() string       { return h.title }
+func (h searchHeader) Description() string { return "" }
+
+type searchItem struct {
+	jellyfin.MusicItem
+}
+
+func (i searchItem) FilterValue() string { return i.Name }
+func (i searchItem) Title() string       { return i.Name }
+func (i searchItem) Description() string {
+	switch i.Type {
+	case "MusicAlbum":
+		return i.AlbumArtist
+	case "Audio":
+		return strings.TrimSpace(i.AlbumArtist + " · " + i.Album)
+	}
+	return ""
+}
+
+type searchDelegate struct{}
+
+func (d searchDelegate) Height() int                             { return 1 }
+func (d searchDelegate) Spacing() int                            { return 0 }
+func (d searchDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d searchDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	switch item := listItem.(type) {
+	case searchHeader:
+		fmt.Fprint(w, albumHeaderStyle.MarginTop(0).Render(item.title))
+	case searchItem:
+		line := item.Name
+		if desc := item.Description(); desc != "" {
+			line += "  " + helpStyle.Render(desc)
+		}
+		if index == m.Index() {
+			fmt.Fprint(w, selectedListItemStyle.Render("> "+line))
+		} else {
+			fmt.Fprint(w, listItemStyle.Render("  "+line))
+		}
+	}
+}
+
+func newSearchInput() textinput.Model {
+	t := textinput.New()
+	t.Placeholder = "Artist, album or track"
+	t.CharLimit = 100
+	t.Width = 50
+	t.PromptStyle = inputFocusedStyle
+	t.TextStyle = inputFocusedStyle
+	return t
+}
+
+func newSearchList() list.Model {
+	l := list.New([]list.Item{}, searchDelegate{}, 0, 0)
+	l.SetShowHelp(false)
+	l.SetShowTitle(false)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	return l
+}
+
+func (m Model) openSearch() (Model, tea.Cmd) {
+	m.searchActive = true
+	m.searchInput.SetValue("")
+	m.searchList.SetItems(nil)
+	return m, m.searchInput.Focus()
+}
+
+func (m Model) closeSearch() Model {
+	m.searchActive = false
+	m.searchInput.Blur()
+	return m
+}
+
+func (m Model) search(term string) tea.Cmd {
+	return func() tea.Msg {
+		results, err := m.client.Search(m.ctx, term)
+		if err != nil {
+			return searchResultsMsg{term: term, err: err}
+		}
+		return searchResultsMsg{term: term, results: results}
+	}
+}
+
+func (m *Model) setSearchResults(results *jellyfin.SearchResults) {
+	var items []list.Item
+	groups := []struct {
+		title string
+		items []jellyfin.MusicItem
+	}{
+		{"Artists", results.Artists},
+		{"Albums", results.Albums},
+		{"Tracks", results.Tracks},
+	}
+	for _, g := range groups {
+		if len(g.items) == 0 {
+			continue
+		}
+		items = append(items, searchHeader{title: fmt.Sprintf("%s (%d)", g.title, len(g.items))})
+		for _, it := range g.items {
+			items = append(items, searchItem{it})
+		}
+	}
+	m.searchList.SetItems(items)
+	m.searchList.Select(0)
+	if len(items) > 1 {
+		m.searchList.Select(1)
+	}
+}
+
+// updateSearch handles input while the search overlay is open.
+func (m Model) updateSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case searchResultsMsg:
+		if msg.term != strings.TrimSpace(m.searchInput.Value()) {
+			return m, nil
+		}
+		if msg.err != nil {
+			if !errors.Is(msg.err, context.Canceled) {
+				m.err = msg.err
+			}
+			return m, nil
+		}
+		m.setSearchResults(msg.results)
+		if len(m.searchList.Items()) == 0 {
+			m.notice = fmt.Sprintf("No results for %q", msg.term)
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "esc" {
+			return m.closeSearch(), nil
+		}
+
+		if m.searchInput.Focused() {
+			switch msg.String() {
+			case "enter":
+				term := strings.TrimSpace(m.searchInput.Value())
+				if term == "" {
+					return m, nil
+				}
+				m.notice = ""
+				return m, m.search(term)
+			case "down", "tab":
+				if len(m.searchList.Items()) > 0 {
+					m.searchInput.Blur()
+				}
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.searchInput, cmd = m.searchInput.Update(msg)
+			return m, cmd
+		}
+
+		switch msg.String() {
+		case "tab", "/":
+			return m, m.searchInput.Focus()
+		case "up", "k":
+			if m.searchList.Index() <= 1 {
+				return m, m.searchInput.Focus()
+			}
+		case "enter":
+			if item, ok := m.searchList.SelectedItem().(searchItem); ok {
+				return m.activateSearchItem(item, false)
+			}
+			return m, nil
+		case "A":
+			if item, ok := m.searchList.SelectedItem().(searchItem); ok {
+				return m.activateSearchItem(item, true)
+			}
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.searchList, cmd = m.searchList.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// activateSearchItem jumps to an artist or album in the library panels, or
+// queues a track. next queues the track right after the playing one.
+func (m Model) activateSearchItem(item searchItem, next bool) (tea.Model, tea.Cmd) {
+	switch item.Type {
+	case "MusicArtist":
+		m = m.closeSearch()
+		m.browse = browseArtists
+		m.browseMenuActive = false
+		m.currentArtist = &item.MusicItem
+		m.selectArtist(item.ID)
+		m.panelFocus = focusTracks
+		m.showQueue = false
+		cmd := m.loadAlbums(item.ID)
+		return m, cmd
+
+	case "MusicAlbum":
+		m = m.closeSearch()
+		m.browse = browseArtists
+		m.browseMenuActive = false
+		m.panelFocus = focusTracks
+		m.showQueue = false
+		if len(item.AlbumArtists) == 0 {
+			m.albums = []jellyfin.MusicItem{item.MusicItem}
+			m.selectedAlbumIndex = 0
+			cmd := m.loadTracks(item.ID)
+			return m, cmd
+		}
+		artist := item.AlbumArtists[0]
+		m.currentArtist = &jellyfin.MusicItem{ID: artist.ID, Name: artist.Name, Type: "MusicArtist"}
+		m.selectArtist(artist.ID)
+		m.pendingAlbumID = item.ID
+		cmd := m.loadAlbums(artist.ID)
+		return m, cmd
+
+	case "Audio":
+		cmd := m.enqueue(m.playerTrack(item.MusicItem), next)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// selectArtist moves the artist list cursor to the artist with the given ID.
+func (m *Model) selectArtist(id string) {
+	for i, it := range m.artistList.Items() {
+		if a, ok := it.(musicItem); ok && a.ID == id {
+			m.artistList.Select(i)
+			return
+		}
+	}
+}
+
+func (m Model) viewSearch() string {
+	width := m.width * 2 / 3
+	if width < 40 {
+		width = 40
+	}
+	height := m.height - 12
+	if height < 5 {
+		height = 5
+	}
+	m.searchList.SetSize(width-6, height)
+
+	header := titleStyle.Render("Search library")
+	input := m.searchInput.View()
+	results := m.searchList.View()
+	if len(m.searchList.Items()) == 0 {
+		results = helpStyle.Render("Type a search and press Enter")
+	}
+	hint := helpStyle.Render("[Enter] open / add to queue  [Shift+A] play next  [Tab] switch  [Esc] close")
+	if m.notice != "" {
+		hint = helpStyle.Render(m.notice)
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, header, "", input, "", results, "", hint)
+	return m.placeModal(content, width)
+}
@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/cedev-1/jellyfin-mustui/internal/config"
+	"github.com/cedev-1/jellyfin-mustui/internal/player"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// stateSaveInterval is how often the queue and position are saved while
+// the app runs, on top of the save on quit.
+const stateSaveInterval = 30 * time.Second
+
+type sessionRestoredMsg struct {
+	track    *player.Track
+	position time.Duration
+	err      error
+}
+
+type autosaveMsg struct{}
+
+// restoreSession loads the queue saved by the previous run and puts the
+// player back where it was, paused.
+func (m Model) restoreSession() tea.Msg {
+	snap := m.local.Snapshot()
+	if err := config.LoadState(m.cfg.Profile, &snap); err != nil {
+		return sessionRestoredMsg{err: err}
+	}
+	for i := range snap.Queue {
+		snap.Queue[i].URL = m.client.GetAudioStreamURL(snap.Queue[i].ID, player.SupportedContainers)
+	}
+
+	err := m.local.Restore(snap)
+	return sessionRestoredMsg{
+		track:    m.local.GetCurrentTrack(),
+		position: m.local.GetPosition(),
+		err:      err,
+	}
+}
+
+func (m Model) autosaveCmd() tea.Cmd {
+	return tea.Tick(stateSaveInterval, func(time.Time) tea.Msg {
+		return autosaveMsg{}
+	})
+}
+
+// saveSessionCmd takes a snapshot now and writes it in the background, so
+// the snapshot always lands in the file of the profile it belongs to.
+func (m Model) saveSessionCmd() tea.Cmd {
+	snap, ok := m.sessionSnapshot()
+	if !ok {
+		return nil
+	}
+	profile := m.cfg.Profile
+	return func() tea.Msg {
+		if err := config.SaveState(profile, snap); err != nil {
+			return errMsg(err)
+		}
+		return nil
+	}
+}
+
+// saveSession writes the player state, unless the previous session has not
+// been restored yet and would be overwritten.
+func (m Model) saveSession() error {
+	snap, ok := m.sessionSnapshot()
+	if !ok {
+		return nil
+	}
+	return config.SaveState(m.cfg.Profile, snap)
+}
+
+// sessionSnapshot returns the player state to save, and false when it must
+// not replace the saved session.
+func (m Model) sessionSnapshot() (player.Snapshot, bool) {
+	if m.local == nil || !(m.sessionRestored || m.restoreFailed) {
+		return player.Snapshot{}, false
+	}
+	snap := m.local.Snapshot()
+	if !m.sessionRestored && len(snap.Queue) == 0 {
+		return snap, false
+	}
+	return snap, true
+}
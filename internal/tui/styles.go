@@ -61,6 +61,9 @@ var (
 	helpStyle = lipgloss.NewStyle().
 			Foreground(colorSubtext)
 
+	favoriteStyle = lipgloss.NewStyle().
+			Foreground(colorError)
+
 	panelStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(colorSubtext).